@@ -0,0 +1,236 @@
+package zord
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/7fffffff/jsonconv"
+)
+
+var (
+	errInvalidNumber = errors.New("canonical: number out of range")
+	errInvalidString = errors.New("canonical: invalid string")
+)
+
+// Canonicalize returns event, a JSON object, in the JSON Canonicalization
+// Scheme (JCS) form defined by RFC 8785: keys sorted by their UTF-16 code
+// units at every depth, numbers and strings in their normalized encodings,
+// and no whitespace. It returns an error if event isn't a single valid JSON
+// object, or if any object within it has duplicate keys.
+func Canonicalize(event []byte) ([]byte, error) {
+	fields, err := ParseFields(event)
+	if err != nil {
+		return nil, err
+	}
+	return appendCanonical(make([]byte, 0, len(event)), fields)
+}
+
+// appendCanonical appends the canonical form of the object made of fields to
+// dest
+func appendCanonical(dest []byte, fields []Field) ([]byte, error) {
+	return appendCanonicalMembers(dest, fields, func(dest []byte, i int) ([]byte, error) {
+		return appendCanonicalValue(dest, fields[i].Value)
+	})
+}
+
+// appendCanonicalMembers appends the object made of fields to dest, sorted
+// by key. appendValue appends the canonical form of the value of fields[i].
+func appendCanonicalMembers(dest []byte, fields []Field, appendValue func(dest []byte, i int) ([]byte, error)) ([]byte, error) {
+	order := make([]int, len(fields))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return lessUTF16(fields[order[i]].Key, fields[order[j]].Key)
+	})
+	dest = append(dest, '{')
+	for i, index := range order {
+		field := fields[index]
+		if i > 0 {
+			if field.Key == fields[order[i-1]].Key {
+				return dest, errDuplicateKey
+			}
+			dest = append(dest, ',')
+		}
+		if !validCanonicalKey(field) {
+			return dest, errInvalidString
+		}
+		dest = appendCanonicalString(dest, field.Key)
+		dest = append(dest, ':')
+		var err error
+		dest, err = appendValue(dest, index)
+		if err != nil {
+			return dest, err
+		}
+	}
+	return append(dest, '}'), nil
+}
+
+// appendCanonicalValue appends the canonical form of the JSON encoded value
+// to dest
+func appendCanonicalValue(dest, value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return dest, errInvalidString
+	}
+	switch value[0] {
+	case '{', '[':
+		p := &parser{}
+		nodes, _, err := p.parseTree(0, value, 0)
+		if err != nil {
+			return dest, err
+		}
+		return appendCanonicalNode(dest, nodes, 0)
+	default:
+		return appendCanonicalScalar(dest, value)
+	}
+}
+
+// appendCanonicalNode appends the canonical form of nodes[i] to dest
+func appendCanonicalNode(dest []byte, nodes []valueNode, i int) ([]byte, error) {
+	value := nodes[i].value
+	switch value[0] {
+	case '{':
+		members := make([]Field, 0, nodes[i].children)
+		indexes := make([]int, 0, nodes[i].children)
+		for child := firstChild(nodes, i); child >= 0; child = nodes[child].next {
+			key, ok := jsonconv.Unquote(nodes[child].key)
+			if !ok {
+				return dest, errInvalidString
+			}
+			members = append(members, Field{Key: key, keyUnquoted: key, keyBytes: nodes[child].key})
+			indexes = append(indexes, child)
+		}
+		return appendCanonicalMembers(dest, members, func(dest []byte, member int) ([]byte, error) {
+			return appendCanonicalNode(dest, nodes, indexes[member])
+		})
+	case '[':
+		dest = append(dest, '[')
+		var err error
+		for child := firstChild(nodes, i); child >= 0; child = nodes[child].next {
+			if child != i+1 {
+				dest = append(dest, ',')
+			}
+			dest, err = appendCanonicalNode(dest, nodes, child)
+			if err != nil {
+				return dest, err
+			}
+		}
+		return append(dest, ']'), nil
+	default:
+		return appendCanonicalScalar(dest, value)
+	}
+}
+
+// appendCanonicalScalar appends the canonical form of the string, number,
+// boolean or null in value to dest
+func appendCanonicalScalar(dest, value []byte) ([]byte, error) {
+	switch value[0] {
+	case '"':
+		// Unquote replaces invalid UTF-8 and lone surrogates with U+FFFD,
+		// so the literal has to be checked first
+		if !validStringLiteral(value) {
+			return dest, errInvalidString
+		}
+		s, ok := jsonconv.Unquote(value)
+		if !ok {
+			return dest, errInvalidString
+		}
+		return appendCanonicalString(dest, s), nil
+	case 't', 'f', 'n':
+		return append(dest, value...), nil
+	default:
+		return appendCanonicalNumber(dest, value)
+	}
+}
+
+// validStringLiteral reports whether literal is a single JSON string with
+// valid UTF-8 and no lone surrogate escapes
+func validStringLiteral(literal []byte) bool {
+	p := &parser{ValidateUTF8: true}
+	end, err := p.parseString(literal, 0)
+	return err == nil && end == len(literal)
+}
+
+// validCanonicalKey reports whether the key of field can be represented
+// exactly. Keys that weren't renamed are checked in their original form.
+func validCanonicalKey(field Field) bool {
+	if field.keyBytes != nil && field.Key == field.keyUnquoted {
+		return validStringLiteral(field.keyBytes)
+	}
+	return utf8.ValidString(field.Key)
+}
+
+// appendCanonicalNumber appends the number in value to dest, encoded like
+// ECMAScript's Number.prototype.toString
+func appendCanonicalNumber(dest, value []byte) ([]byte, error) {
+	f, err := strconv.ParseFloat(string(value), 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return dest, errInvalidNumber
+	}
+	if f == 0 {
+		return append(dest, '0'), nil
+	}
+	if f < 0 {
+		dest = append(dest, '-')
+		f = -f
+	}
+	format := byte('e')
+	if 1e-6 <= f && f < 1e21 {
+		format = 'f'
+	}
+	dest = strconv.AppendFloat(dest, f, format, -1, 64)
+	if format == 'e' {
+		// strconv writes exponents with at least two digits, like 1e+09,
+		// but ECMAScript writes 1e+9
+		n := len(dest)
+		if dest[n-2] == '0' && (dest[n-3] == '+' || dest[n-3] == '-') {
+			dest = append(dest[:n-2], dest[n-1])
+		}
+	}
+	return dest, nil
+}
+
+// appendCanonicalString appends s to dest as a JSON string literal, escaping
+// only what RFC 8785 requires
+func appendCanonicalString(dest []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	dest = append(dest, '"')
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case b == '"' || b == '\\':
+			dest = append(dest, '\\', b)
+		case b >= 0x20:
+			dest = append(dest, b)
+		case b == '\b':
+			dest = append(dest, '\\', 'b')
+		case b == '\t':
+			dest = append(dest, '\\', 't')
+		case b == '\n':
+			dest = append(dest, '\\', 'n')
+		case b == '\f':
+			dest = append(dest, '\\', 'f')
+		case b == '\r':
+			dest = append(dest, '\\', 'r')
+		default:
+			dest = append(dest, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+		}
+	}
+	return append(dest, '"')
+}
+
+// lessUTF16 reports whether a sorts before b when compared by their UTF-16
+// code units
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
@@ -0,0 +1,107 @@
+// Command zord works with logs written by zord.Writer.
+//
+// Usage:
+//
+//	zord check [file]
+//	zord verify [-key hash] [-seed seed] [file]
+//
+// check parses a sequence of JSON objects, such as a log or pretty-printed
+// events, reading from file or standard input, and reports the line, column
+// and an excerpt of the first error.
+//
+// verify checks the hash chain of a log written by a zord.Writer with a
+// HashChain, reading from file or standard input, and reports the first
+// broken link.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/7fffffff/zord"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "check":
+		os.Exit(check(os.Args[2:]))
+	case "verify":
+		os.Exit(verify(os.Args[2:]))
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: zord check [file]")
+	fmt.Fprintln(os.Stderr, "       zord verify [-key hash] [-seed seed] [file]")
+	os.Exit(2)
+}
+
+func verify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	key := flags.String("key", zord.DefaultHashChainKey, "key of the hash field")
+	seed := flags.String("seed", "", "previous hash of the first event")
+	flags.Parse(args)
+	name, r, err := open(flags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer r.Close()
+	if err := zord.VerifyHashChain(r, *key, *seed); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	fmt.Printf("%s: ok\n", name)
+	return 0
+}
+
+func check(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Parse(args)
+	name, r, err := open(flags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	events, err := zord.ParseEvents(data)
+	if err != nil {
+		var posErr zord.PositionError
+		if errors.As(err, &posErr) && posErr.Line() > 0 {
+			fmt.Fprintf(os.Stderr, "%s:%d:%d: %v\n%s\n", name, posErr.Line(), posErr.Column(), err, posErr.Excerpt())
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		}
+		return 1
+	}
+	fmt.Printf("%s: %d events ok\n", name, len(events))
+	return 0
+}
+
+// open opens the file named by the only argument in flags, or returns
+// standard input if there are no arguments
+func open(flags *flag.FlagSet) (string, io.ReadCloser, error) {
+	switch flags.NArg() {
+	case 0:
+		return "stdin", io.NopCloser(os.Stdin), nil
+	case 1:
+		f, err := os.Open(flags.Arg(0))
+		return flags.Arg(0), f, err
+	default:
+		usage()
+		return "", nil, nil
+	}
+}
@@ -0,0 +1,149 @@
+package zord
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Collapser suppresses consecutive duplicate events. Events are duplicates
+// if they have the same fields in the same order, ignoring the fields named
+// by IgnoreKeys.
+//
+// The first event of a run of duplicates is written as usual. When the run
+// ends, because a different event is written, Timeout passes without another
+// duplicate, or Flush is called, a copy of the last duplicate is written with
+// extra fields: "repeated", the number of suppressed duplicates, and
+// "first_time" and "last_time", the timestamps of the first and last
+// suppressed duplicates.
+//
+// The follow-up event may be written by a timer when Timeout is set. It's
+// serialized with the Writer's own writes, including Sampler summaries, so
+// Output doesn't need to be safe for concurrent use unless it's also
+// written to by other means.
+//
+// A Collapser must not be shared by several Writers or copied after first
+// use.
+type Collapser struct {
+	IgnoreKeys []string      // keys of volatile fields. If nil, only the timestamp is ignored
+	Timeout    time.Duration // time after which a run of duplicates ends, if > 0
+
+	mu        sync.Mutex
+	identity  []byte
+	last      []byte // the last suppressed duplicate
+	repeated  int
+	firstTime []byte
+	lastTime  []byte
+	timer     *time.Timer
+	writeFn   func([]byte) (int, error)
+}
+
+// write writes event with writeFn, unless it's a duplicate of the previous
+// event
+func (c *Collapser) write(event []byte, writeFn func([]byte) (int, error)) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeFn = writeFn
+	identity, ts, ok := c.identify(event)
+	if ok && c.identity != nil && bytes.Equal(identity, c.identity) {
+		if c.repeated == 0 {
+			c.firstTime = ts
+		}
+		c.repeated++
+		c.lastTime = ts
+		c.last = append(c.last[:0], event...)
+		if c.Timeout > 0 {
+			if c.timer == nil {
+				c.timer = time.AfterFunc(c.Timeout, c.timeout)
+			} else {
+				c.timer.Reset(c.Timeout)
+			}
+		}
+		return len(event), nil
+	}
+	if _, err = c.endRun(); err != nil {
+		return 0, err
+	}
+	if ok {
+		c.identity = identity
+	}
+	return writeFn(event)
+}
+
+// Flush ends the current run of duplicates, writing the follow-up event if
+// any duplicates were suppressed.
+func (c *Collapser) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.endRun()
+	return err
+}
+
+func (c *Collapser) timeout() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.endRun()
+}
+
+// endRun writes the follow-up event for the current run, if there is one,
+// and forgets the run. c.mu must be held.
+func (c *Collapser) endRun() (int, error) {
+	c.identity = nil
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	if c.repeated == 0 {
+		return 0, nil
+	}
+	repeated := c.repeated
+	c.repeated = 0
+	p := &parser{}
+	pairs, _, err := p.parse(c.last)
+	if err != nil {
+		return 0, err
+	}
+	fields := fieldsFromPairs(pairs)
+	fields = append(fields,
+		Field{Key: "repeated", Value: strconv.AppendInt(nil, int64(repeated), 10)},
+		Field{Key: "first_time", Value: c.firstTime},
+		Field{Key: "last_time", Value: c.lastTime},
+	)
+	return c.writeFn(reorderFields(nil, fields, nil))
+}
+
+// identify returns the fields of event that identify duplicates, and the
+// value of its timestamp field (or the current time)
+func (c *Collapser) identify(event []byte) (identity []byte, ts []byte, ok bool) {
+	p := &parser{}
+	pairs, n, err := p.parse(event)
+	if err != nil || skipWhitespace(event, n) < len(event) {
+		return nil, nil, false
+	}
+	ignoreKeys := c.IgnoreKeys
+	if ignoreKeys == nil {
+		ignoreKeys = []string{zerolog.TimestampFieldName}
+	}
+	identity = make([]byte, 0, len(event))
+pairs:
+	for _, pair := range pairs {
+		if pair.keyUnquoted == zerolog.TimestampFieldName && ts == nil {
+			ts = append([]byte(nil), pair.valueBytes...)
+		}
+		for _, key := range ignoreKeys {
+			if pair.keyUnquoted == key {
+				continue pairs
+			}
+		}
+		identity = append(identity, pair.keyBytes...)
+		identity = append(identity, ':')
+		identity = append(identity, pair.valueBytes...)
+		identity = append(identity, ',')
+	}
+	if ts == nil {
+		ts = appendTime(nil, time.Now(), zerolog.TimeFieldFormat)
+	}
+	return identity, ts, true
+}
@@ -0,0 +1,126 @@
+package zord
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCollapser(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Collapse = &Collapser{}
+	events := []string{
+		`{"message":"retrying","time":1}`,
+		`{"message":"retrying","time":2}`,
+		`{"message":"retrying","time":3}`,
+		`{"message":"retrying","time":4}`,
+		`{"message":"done","time":5}`,
+		`{"message":"done","time":6}`,
+		`not json`,
+		`not json`,
+	}
+	for _, event := range events {
+		writer.Write([]byte(event))
+	}
+	expected := "{\"time\":1,\"message\":\"retrying\"}\n" +
+		"{\"time\":4,\"message\":\"retrying\",\"repeated\":3,\"first_time\":2,\"last_time\":4}\n" +
+		"{\"time\":5,\"message\":\"done\"}\n" +
+		"{\"time\":6,\"message\":\"done\",\"repeated\":1,\"first_time\":6,\"last_time\":6}\n" +
+		"not jsonnot json"
+	if buf.String() != expected {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+}
+
+func TestCollapserIgnoreKeys(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Collapse = &Collapser{IgnoreKeys: []string{"time", "attempt"}}
+	writer.Write([]byte(`{"message":"retrying","attempt":1,"time":1}`))
+	writer.Write([]byte(`{"message":"retrying","attempt":2,"time":2}`))
+	writer.Write([]byte(`{"message":"retrying","attempt":3}`))
+	if err := writer.Collapse.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\"time\":1,\"message\":\"retrying\",\"attempt\":1}\n" +
+		"{\"message\":\"retrying\",\"attempt\":3,\"repeated\":2,\"first_time\":2,\"last_time\":"
+	if !bytes.HasPrefix(buf.Bytes(), []byte(expected)) {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+	buf.Reset()
+	if err := writer.Collapse.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestCollapserTimeout(t *testing.T) {
+	buf := &lockedBuffer{}
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Collapse = &Collapser{Timeout: 10 * time.Millisecond}
+	writer.Write([]byte(`{"message":"retrying","time":1}`))
+	writer.Write([]byte(`{"message":"retrying","time":2}`))
+	expected := "{\"time\":1,\"message\":\"retrying\"}\n" +
+		"{\"time\":2,\"message\":\"retrying\",\"repeated\":1,\"first_time\":2,\"last_time\":2}\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for buf.String() != expected && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if buf.String() != expected {
+		t.Fatalf("unexpected: %s", buf.String())
+	}
+	// the run ended, so the next duplicate starts a new run
+	writer.Write([]byte(`{"message":"retrying","time":3}`))
+	if buf.String() != expected+"{\"time\":3,\"message\":\"retrying\"}\n" {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+}
+
+func TestCollapserTimeoutRace(t *testing.T) {
+	// the follow-up events written by the timer, and sampler summaries,
+	// don't race with the Writer's writes, so a plain buffer can be the
+	// output
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Collapse = &Collapser{Timeout: time.Millisecond}
+	writer.Sampler = &Sampler{Rate: 100, Burst: 3, SummaryInterval: time.Millisecond}
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		// let both timers fire between bursts
+		for i := 0; i < 5; i++ {
+			writer.Write([]byte(`{"message":"retrying"}`))
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	writer.Sampler.Flush()
+	writer.Collapse.Flush()
+	if !strings.Contains(buf.String(), `"repeated"`) {
+		t.Errorf("no follow-up event was written")
+	}
+}
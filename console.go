@@ -0,0 +1,140 @@
+package zord
+
+import (
+	"strings"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// ANSI colors used in console output, matching zerolog.ConsoleWriter
+const (
+	colorReset    = 0
+	colorRed      = 31
+	colorGreen    = 32
+	colorYellow   = 33
+	colorMagenta  = 35
+	colorCyan     = 36
+	colorDarkGray = 90
+)
+
+// appendConsole appends a human readable line made of fields to dest, in
+// the style of zerolog.ConsoleWriter:
+//
+//	<time> |<LVL>| <message> key=value key=value
+//
+// Missing parts are left out. The remaining fields are written with the fields named in firstKeys first,
+// then in their original order. String values are unquoted unless they
+// contain spaces or special characters, other values are written as JSON.
+func appendConsole(dest []byte, fields []Field, firstKeys []string, color bool) []byte {
+	var timestamp, level, message []byte
+	rest := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		switch {
+		case field.Key == zerolog.TimestampFieldName && timestamp == nil:
+			timestamp = field.Value
+		case field.Key == zerolog.LevelFieldName && level == nil:
+			level = field.Value
+		case field.Key == zerolog.MessageFieldName && message == nil:
+			message = field.Value
+		default:
+			rest = append(rest, field)
+		}
+	}
+	if timestamp != nil {
+		dest = appendColor(dest, consoleValue(timestamp), colorDarkGray, color)
+		dest = append(dest, ' ')
+	}
+	levelText, levelColor := "????", colorReset
+	if s, ok := jsonconv.Unquote(level); ok && level != nil {
+		levelText = strings.ToUpper(s)
+		if len(levelText) > 4 {
+			levelText = levelText[:4]
+		}
+		levelColor = consoleLevelColor(s)
+	}
+	dest = append(dest, '|')
+	dest = appendColor(dest, []byte(levelText), levelColor, color)
+	dest = append(dest, '|')
+	if message != nil {
+		dest = append(dest, ' ')
+		if s, ok := jsonconv.Unquote(message); ok {
+			dest = append(dest, s...)
+		} else {
+			dest = append(dest, message...)
+		}
+	}
+	for _, field := range orderFields(rest, firstKeys) {
+		dest = append(dest, ' ')
+		dest = appendColor(dest, []byte(field.Key), colorCyan, color)
+		dest = append(dest, '=')
+		dest = append(dest, consoleValue(field.Value)...)
+	}
+	return dest
+}
+
+// consoleValue returns the console representation of a JSON encoded value
+func consoleValue(value []byte) []byte {
+	if !isStringLiteral(value) {
+		return value
+	}
+	s, ok := jsonconv.Unquote(value)
+	if !ok || s == "" {
+		return value
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c > '~' || c == '\\' || c == '"' {
+			return value
+		}
+	}
+	return []byte(s)
+}
+
+func appendColor(dest, s []byte, color int, enabled bool) []byte {
+	if !enabled {
+		return append(dest, s...)
+	}
+	dest = append(dest, "\x1b["...)
+	dest = append(dest, byte('0'+color/10), byte('0'+color%10), 'm')
+	dest = append(dest, s...)
+	return append(dest, "\x1b[0m"...)
+}
+
+func consoleLevelColor(level string) int {
+	switch level {
+	case zerolog.DebugLevel.String():
+		return colorMagenta
+	case zerolog.InfoLevel.String():
+		return colorGreen
+	case zerolog.WarnLevel.String():
+		return colorYellow
+	case zerolog.ErrorLevel.String(), zerolog.FatalLevel.String(), zerolog.PanicLevel.String():
+		return colorRed
+	default:
+		return colorReset
+	}
+}
+
+// orderFields returns fields with the fields named in firstKeys moved to the
+// beginning, in the same order as reorderFields writes them.
+func orderFields(fields []Field, firstKeys []string) []Field {
+	if len(firstKeys) == 0 {
+		return fields
+	}
+	ordered := make([]Field, 0, len(fields))
+	moved := make([]bool, len(fields))
+	for _, key := range firstKeys {
+		for i, field := range fields {
+			if field.Key == key && !moved[i] {
+				ordered = append(ordered, field)
+				moved[i] = true
+			}
+		}
+	}
+	for i, field := range fields {
+		if !moved[i] {
+			ordered = append(ordered, field)
+		}
+	}
+	return ordered
+}
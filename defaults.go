@@ -2,7 +2,9 @@ package zord
 
 import "github.com/rs/zerolog"
 
-const defaultMaxDepth int = 64
+// defaultMaxDepth is the maximum nesting depth of events, matching
+// encoding/json
+const defaultMaxDepth int = 10000
 
 func DefaultFirstKeys() []string {
 	return []string{
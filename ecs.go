@@ -0,0 +1,93 @@
+package zord
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// ECSVersion is the version of Elastic Common Schema that ECSTransformer
+// targets.
+const ECSVersion = "8.11.0"
+
+// ECSTransformer is a Transformer that renames zerolog's standard fields to
+// their Elastic Common Schema (ECS) equivalents:
+//
+//	time    -> @timestamp
+//	level   -> log.level
+//	message -> message
+//	error   -> error.message
+//	caller  -> log.origin.file.name, log.origin.file.line
+//
+// The renamed keys are dotted, so ECSTransformer is meant to be used together
+// with an Unflattener, as in NewECSWriter. Other fields are left as they are.
+type ECSTransformer struct{}
+
+// Transform renames the standard fields in fields.
+func (ECSTransformer) Transform(fields []Field) ([]Field, error) {
+	for i := 0; i < len(fields); i++ {
+		switch fields[i].Key {
+		case zerolog.TimestampFieldName:
+			fields[i].Key = "@timestamp"
+		case zerolog.LevelFieldName:
+			fields[i].Key = "log.level"
+		case zerolog.MessageFieldName:
+			fields[i].Key = "message"
+		case zerolog.ErrorFieldName:
+			fields[i].Key = "error.message"
+		case zerolog.CallerFieldName:
+			fields[i].Key = "log.origin.file.name"
+			file, line, ok := splitCaller(fields[i].Value)
+			if !ok {
+				continue
+			}
+			fields[i].Value = jsonconv.Quote(file)
+			fields = append(fields, Field{})
+			copy(fields[i+2:], fields[i+1:])
+			fields[i+1] = Field{
+				Key:   "log.origin.file.line",
+				Value: strconv.AppendInt(nil, int64(line), 10),
+			}
+			i++
+		}
+	}
+	return fields, nil
+}
+
+// splitCaller splits a caller value formatted as "file:line"
+func splitCaller(value []byte) (file string, line int, ok bool) {
+	if !isStringLiteral(value) {
+		return "", 0, false
+	}
+	caller, ok := jsonconv.Unquote(value)
+	if !ok {
+		return "", 0, false
+	}
+	i := strings.LastIndexByte(caller, ':')
+	if i < 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(caller[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return caller[:i], line, true
+}
+
+// NewECSWriter creates a new Writer that writes Elastic Common Schema (ECS)
+// documents. The output writer is os.Stderr.
+//
+// Standard fields are renamed by ECSTransformer, the timestamp is written
+// in RFC 3339 format in UTC, "ecs.version" is added, and dotted keys are
+// grouped into nested objects.
+func NewECSWriter() *Writer {
+	w := NewWriter()
+	w.FirstKeys = []string{"@timestamp", "log", "message", "error", "ecs"}
+	w.Transformers = []Transformer{ECSTransformer{}}
+	w.Fields = []Field{StringField("ecs.version", ECSVersion)}
+	w.NormalizeTime = &TimeNormalizer{Key: "@timestamp"}
+	w.Unflatten = &Unflattener{}
+	return w
+}
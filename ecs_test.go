@@ -0,0 +1,72 @@
+package zord
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+type ecsTest struct {
+	desc     string
+	obj      []byte
+	expected []byte
+}
+
+var ecsTests = []ecsTest{
+	{
+		desc:     "minimal",
+		obj:      []byte(`{"level":"info","time":"2006-01-02T15:04:05-07:00","message":"hello"}`),
+		expected: []byte(`{"@timestamp":"2006-01-02T22:04:05Z","log":{"level":"info"},"message":"hello","ecs":{"version":"8.11.0"}}`),
+	},
+	{
+		desc:     "unix time",
+		obj:      []byte(`{"level":"debug","time":1136214245,"message":"hello"}`),
+		expected: []byte(`{"@timestamp":"2006-01-02T15:04:05Z","log":{"level":"debug"},"message":"hello","ecs":{"version":"8.11.0"}}`),
+	},
+	{
+		desc:     "error and caller",
+		obj:      []byte(`{"level":"error","error":"oh no!","caller":"/src/main.go:42","time":"2006-01-02T15:04:05Z","message":"failed"}`),
+		expected: []byte(`{"@timestamp":"2006-01-02T15:04:05Z","log":{"level":"error","origin":{"file":{"name":"/src/main.go","line":42}}},"message":"failed","error":{"message":"oh no!"},"ecs":{"version":"8.11.0"}}`),
+	},
+	{
+		desc:     "custom fields",
+		obj:      []byte(`{"level":"warn","service.name":"greeter","http":{"request":{"method":"GET"}},"http.response.status_code":200,"message":"slow"}`),
+		expected: []byte(`{"log":{"level":"warn"},"message":"slow","ecs":{"version":"8.11.0"},"service":{"name":"greeter"},"http":{"request":{"method":"GET"},"response":{"status_code":200}}}`),
+	},
+	{
+		desc:     "caller without line",
+		obj:      []byte(`{"caller":"main.go","message":"hi"}`),
+		expected: []byte(`{"log":{"origin":{"file":{"name":"main.go"}}},"message":"hi","ecs":{"version":"8.11.0"}}`),
+	},
+}
+
+func TestECSWriter(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewECSWriter()
+	writer.Output = buf
+	for _, test := range ecsTests {
+		buf.Reset()
+		_, err := writer.Write(test.obj)
+		if err != nil {
+			t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			continue
+		}
+		result := bytes.TrimRight(buf.Bytes(), "\n")
+		if !bytes.Equal(test.expected, result) {
+			t.Errorf("test \"%s\" unexpected: %s", test.desc, string(result))
+			continue
+		}
+		var doc struct {
+			ECS struct {
+				Version string `json:"version"`
+			} `json:"ecs"`
+		}
+		if err := json.Unmarshal(result, &doc); err != nil {
+			t.Errorf("test \"%s\" invalid JSON: %v", test.desc, err)
+			continue
+		}
+		if doc.ECS.Version != ECSVersion {
+			t.Errorf("test \"%s\" missing ecs.version", test.desc)
+		}
+	}
+}
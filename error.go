@@ -1,7 +1,11 @@
 package zord
 
 import (
+	"bytes"
+	"errors"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type errorAt interface {
@@ -9,22 +13,54 @@ type errorAt interface {
 	Pos() int
 }
 
+// PositionError is implemented by errors about a problem at a position
+// within an event, such as parse errors. Line, Column and Excerpt are only
+// available if the error was returned by a function that had the whole input
+// (ParseFields, ParseEvents, Writer in strict mode, or passed to
+// Writer.OnError); otherwise Line and Column return 0 and Excerpt returns "".
+type PositionError interface {
+	error
+	Pos() int        // byte offset of the problem
+	Line() int       // line number of the problem, starting at 1
+	Column() int     // column of the problem in characters, starting at 1
+	Excerpt() string // the part of the line around the problem, and a line with a caret marking it
+}
+
 type errAt struct {
-	err error
-	pos int
+	err     error
+	pos     int
+	line    int
+	column  int
+	excerpt string
 }
 
 func (e *errAt) Error() string {
+	s := "error at position " + strconv.Itoa(e.pos)
+	if e.line > 0 {
+		s += " (line " + strconv.Itoa(e.line) + ", column " + strconv.Itoa(e.column) + ")"
+	}
 	if e.err == nil {
-		return "error at position " + strconv.Itoa(e.pos)
+		return s
 	}
-	return "error at position " + strconv.Itoa(e.pos) + ": " + e.err.Error()
+	return s + ": " + e.err.Error()
 }
 
 func (e *errAt) Pos() int {
 	return e.pos
 }
 
+func (e *errAt) Line() int {
+	return e.line
+}
+
+func (e *errAt) Column() int {
+	return e.column
+}
+
+func (e *errAt) Excerpt() string {
+	return e.excerpt
+}
+
 func (e *errAt) Unwrap() error {
 	return e.err
 }
@@ -43,3 +79,74 @@ func parseErrorAt(pos int, err error) errorAt {
 		pos: pos,
 	}
 }
+
+// shiftPos adds offset to the position of err, for errors from parsing a
+// part of a larger buffer, and returns err.
+func shiftPos(err error, offset int) error {
+	var e *errAt
+	if errors.As(err, &e) {
+		e.pos += offset
+	}
+	return err
+}
+
+// excerptWidth is the maximum number of characters shown on either side of
+// the problem in excerpts
+const excerptWidth = 30
+
+// withContext adds the line, column and excerpt of the position of err
+// within buf, if err has a position, and returns err.
+func withContext(err error, buf []byte) error {
+	var e *errAt
+	if !errors.As(err, &e) || e.pos < 0 || e.pos > len(buf) {
+		return err
+	}
+	lineStart := 0
+	e.line = 1
+	for i := 0; i < e.pos; i++ {
+		if buf[i] == '\n' {
+			e.line++
+			lineStart = i + 1
+		}
+	}
+	lineEnd := len(buf)
+	if i := bytes.IndexByte(buf[e.pos:], '\n'); i >= 0 {
+		lineEnd = e.pos + i
+	}
+	before := buf[lineStart:e.pos]
+	after := buf[e.pos:lineEnd]
+	e.column = utf8.RuneCount(before) + 1
+
+	var excerpt strings.Builder
+	var caret strings.Builder
+	if n := utf8.RuneCount(before); n > excerptWidth {
+		for ; n > excerptWidth; n-- {
+			_, size := utf8.DecodeRune(before)
+			before = before[size:]
+		}
+		excerpt.WriteString("...")
+		caret.WriteString("   ")
+	}
+	excerpt.Write(before)
+	for _, r := range string(before) {
+		if r == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	caret.WriteByte('^')
+	if utf8.RuneCount(after) > excerptWidth {
+		end := 0
+		for n := 0; n < excerptWidth; n++ {
+			_, size := utf8.DecodeRune(after[end:])
+			end += size
+		}
+		excerpt.Write(after[:end])
+		excerpt.WriteString("...")
+	} else {
+		excerpt.Write(after)
+	}
+	e.excerpt = strings.TrimRight(excerpt.String(), "\r") + "\n" + caret.String()
+	return err
+}
@@ -0,0 +1,79 @@
+package zord
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPositionError(t *testing.T) {
+	long := strings.Repeat("x", 40)
+	tests := []struct {
+		desc    string
+		obj     string
+		pos     int
+		line    int
+		column  int
+		excerpt string
+	}{
+		{"single line", `{"a":1,}`, 7, 1, 8, "{\"a\":1,}\n       ^"},
+		{"multiple lines", "{\n\t\"a\": 1,\n\t\"b\" 2\n}", 16, 3, 6, "\t\"b\" 2\n\t    ^"},
+		{"unicode", `{"é":"日本",x}`, 15, 1, 11, "{\"é\":\"日本\",x}\n          ^"},
+		{"eof", "{\n\"a\":", 6, 2, 5, "\"a\":\n    ^"},
+		{"long line", `{"a":"` + long + `",x,"b":"` + long + `"}`, 48, 1, 49, "..." + long[:28] + "\",x,\"b\":\"" + long[:23] + "...\n" + strings.Repeat(" ", 33) + "^"},
+		{"trailing data", "{}\n{}", 3, 2, 1, "{}\n^"},
+	}
+	for _, test := range tests {
+		_, err := ParseFields([]byte(test.obj))
+		var posErr PositionError
+		if !errors.As(err, &posErr) {
+			t.Errorf("test \"%s\" unexpected error: %v", test.desc, err)
+			continue
+		}
+		if posErr.Pos() != test.pos || posErr.Line() != test.line || posErr.Column() != test.column {
+			t.Errorf("test \"%s\" unexpected position: %d %d:%d", test.desc, posErr.Pos(), posErr.Line(), posErr.Column())
+		}
+		if posErr.Excerpt() != test.excerpt {
+			t.Errorf("test \"%s\" unexpected excerpt:\n%s", test.desc, posErr.Excerpt())
+		}
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents([]byte("{\"a\":1}\n{\n  \"b\": 2,\n  \"c\": [3]\n}\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || len(events[1]) != 2 || events[1][1].Key != "c" {
+		t.Errorf("unexpected: %v", events)
+	}
+	_, err = ParseEvents([]byte("{\"a\":1}\n{\n  \"b\": 2\n  \"c\": 3\n}"))
+	var posErr PositionError
+	if !errors.As(err, &posErr) || posErr.Line() != 4 || posErr.Column() != 3 || posErr.Pos() != 21 {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWriterOnError(t *testing.T) {
+	var reported []error
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.OnError = func(event []byte, err error) {
+		reported = append(reported, err)
+	}
+	writer.Write([]byte(`{"a":1}`))
+	writer.Write([]byte("{\"a\":\n1,}"))
+	writer.Write([]byte(`{"a":1} x`))
+	if len(reported) != 2 {
+		t.Fatalf("unexpected errors: %v", reported)
+	}
+	var posErr PositionError
+	if !errors.As(reported[0], &posErr) || posErr.Line() != 2 || posErr.Column() != 3 {
+		t.Errorf("unexpected error: %v", reported[0])
+	}
+	if !errors.As(reported[1], &posErr) || posErr.Column() != 9 || !errors.Is(reported[1], errTrailingData) {
+		t.Errorf("unexpected error: %v", reported[1])
+	}
+}
@@ -0,0 +1,78 @@
+package zord
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// StringField returns a Field with a string value.
+func StringField(key, value string) Field {
+	return Field{Key: key, Value: jsonconv.Quote(value)}
+}
+
+// unquoteValue unquotes JSON strings, and leaves other values as-is
+func unquoteValue(value []byte) []byte {
+	if isStringLiteral(value) {
+		if unquoted, ok := jsonconv.UnquoteBytes(value); ok {
+			return unquoted
+		}
+	}
+	return value
+}
+
+// ComputedField is a field whose value is computed each time Writer writes
+// an event.
+type ComputedField struct {
+	Key   string
+	Value func() []byte // returns a JSON encoded value
+}
+
+// SequenceField returns a ComputedField whose value is a number that
+// increases by one for every event, starting at 1. The sequence is shared
+// by every Writer the ComputedField is added to.
+func SequenceField(key string) ComputedField {
+	var seq uint64
+	return ComputedField{
+		Key: key,
+		Value: func() []byte {
+			return strconv.AppendUint(nil, atomic.AddUint64(&seq, 1), 10)
+		},
+	}
+}
+
+// CounterField returns a ComputedField whose value is the current value of
+// counter. counter must only be modified with the sync/atomic functions.
+func CounterField(key string, counter *uint64) ComputedField {
+	return ComputedField{
+		Key: key,
+		Value: func() []byte {
+			return strconv.AppendUint(nil, atomic.LoadUint64(counter), 10)
+		},
+	}
+}
+
+// WriteTimeField returns a ComputedField whose value is the time at which
+// the event is written, formatted according to zerolog.TimeFieldFormat.
+func WriteTimeField(key string) ComputedField {
+	return ComputedField{
+		Key: key,
+		Value: func() []byte {
+			return appendTime(nil, time.Now(), zerolog.TimeFieldFormat)
+		},
+	}
+}
+
+// appendTime formats t the same way zerolog does. If layout is empty, t is
+// formatted as a unix timestamp
+func appendTime(dest []byte, t time.Time, layout string) []byte {
+	if layout == "" {
+		return strconv.AppendInt(dest, t.Unix(), 10)
+	}
+	dest = append(dest, '"')
+	dest = t.AppendFormat(dest, layout)
+	return append(dest, '"')
+}
@@ -0,0 +1,62 @@
+package zord
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestFields(t *testing.T) {
+	var counter uint64 = 7
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.FirstKeys = []string{"seq", "host", "aaa"}
+	writer.Fields = []Field{
+		StringField("host", "example"),
+		{Key: "env", Value: []byte(`{"name":"dev"}`)},
+	}
+	writer.ComputedFields = []ComputedField{
+		SequenceField("seq"),
+		CounterField("counter", &counter),
+	}
+	expected := [][]byte{
+		[]byte(`{"seq":1,"host":"example","aaa":"foo","bbb":"bar","env":{"name":"dev"},"counter":7}`),
+		[]byte(`{"seq":2,"host":"example","aaa":"foo","bbb":"bar","env":{"name":"dev"},"counter":7}`),
+		[]byte(`{"seq":3,"host":"example","aaa":"foo","bbb":"bar","env":{"name":"dev"},"counter":7}`),
+	}
+	inputs := [][]byte{
+		[]byte(`{"aaa":"foo","bbb":"bar"}`),
+		[]byte(`{"aaa":"foo","bbb":"bar"}`),
+		[]byte(`{"bbb":"bar","aaa":"foo"}`),
+	}
+	for i, obj := range inputs {
+		buf.Reset()
+		if _, err := writer.Write(obj); err != nil {
+			t.Fatal(err)
+		}
+		result := bytes.TrimRight(buf.Bytes(), "\n")
+		if !bytes.Equal(expected[i], result) {
+			t.Errorf("event #%d unexpected: %s", i, string(result))
+		}
+	}
+}
+
+func TestWriteTimeField(t *testing.T) {
+	defer func(format string) {
+		zerolog.TimeFieldFormat = format
+	}(zerolog.TimeFieldFormat)
+	zerolog.TimeFieldFormat = time.RFC3339
+	field := WriteTimeField("written")
+	value := field.Value()
+	if _, err := time.Parse(`"`+time.RFC3339+`"`, string(value)); err != nil {
+		t.Errorf("unexpected: %s", value)
+	}
+	zerolog.TimeFieldFormat = ""
+	value = field.Value()
+	if bytes.IndexByte(value, '"') >= 0 || len(value) == 0 {
+		t.Errorf("unexpected: %s", value)
+	}
+}
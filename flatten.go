@@ -0,0 +1,85 @@
+package zord
+
+import (
+	"strconv"
+
+	"github.com/7fffffff/jsonconv"
+)
+
+// Flattener is a Transformer that expands nested objects into top level
+// fields, with the keys of the nested fields prefixed by the key of their
+// parent and Separator. For example, {"http":{"method":"GET"}} becomes
+// {"http.method":"GET"}.
+//
+// Empty objects and arrays are left as they are, as are values nested more
+// than MaxDepth levels deep.
+type Flattener struct {
+	Separator   string // separates the keys of parents and children. If "", "." is used
+	IndexArrays bool   // whether to also expand arrays, using the element indexes as keys
+	MaxDepth    int    // maximum levels of nesting to expand. If 0, Writer.MaxDepth (or defaultMaxDepth) is used
+}
+
+func (f Flattener) separator() string {
+	if f.Separator == "" {
+		return "."
+	}
+	return f.Separator
+}
+
+// Transform expands the nested objects of fields.
+func (f Flattener) Transform(fields []Field) ([]Field, error) {
+	flattened := make([]Field, 0, len(fields))
+	p := &parser{}
+	if f.MaxDepth > defaultMaxDepth {
+		p.MaxDepth = f.MaxDepth
+	}
+	for _, field := range fields {
+		if !f.expands(field.Value) {
+			flattened = append(flattened, field)
+			continue
+		}
+		nodes, _, err := p.parseTree(0, field.Value, 0)
+		if err != nil {
+			return fields, err
+		}
+		if nodes[0].children == 0 {
+			flattened = append(flattened, field)
+			continue
+		}
+		flattened = f.appendFlattened(flattened, []byte(field.Key), nodes, 0, 1)
+	}
+	return flattened, nil
+}
+
+// expands reports whether value is an object, or an array if f.IndexArrays
+// is set
+func (f Flattener) expands(value []byte) bool {
+	return len(value) > 0 && (value[0] == '{' || (value[0] == '[' && f.IndexArrays))
+}
+
+// appendFlattened appends the fields that nodes[i], named key, expands to.
+// The keys of children are appended to key, so that deep nesting doesn't
+// build every intermediate key.
+func (f Flattener) appendFlattened(dest []Field, key []byte, nodes []valueNode, i int, depth int) []Field {
+	maxDepth := f.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultMaxDepth
+	}
+	node := nodes[i]
+	if depth > maxDepth || node.children == 0 || !f.expands(node.value) {
+		return append(dest, Field{Key: string(key), Value: node.value})
+	}
+	index := 0
+	for child := firstChild(nodes, i); child >= 0; child = nodes[child].next {
+		childKey := append(key, f.separator()...)
+		if node.value[0] == '{' {
+			unquoted, _ := jsonconv.UnquoteBytes(nodes[child].key)
+			childKey = append(childKey, unquoted...)
+		} else {
+			childKey = strconv.AppendInt(childKey, int64(index), 10)
+		}
+		dest = f.appendFlattened(dest, childKey, nodes, child, depth+1)
+		index++
+	}
+	return dest
+}
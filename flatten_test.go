@@ -0,0 +1,85 @@
+package zord
+
+import (
+	"bytes"
+	"testing"
+)
+
+type flattenTest struct {
+	desc      string
+	obj       []byte
+	firstKeys []string
+	flattener Flattener
+	expected  []byte
+}
+
+var flattenTests = []flattenTest{
+	{
+		desc:     "nothing nested",
+		obj:      []byte(`{"aaa":"foo", "bbb":[1,2]}`),
+		expected: []byte(`{"aaa":"foo","bbb":[1,2]}`),
+	},
+	{
+		desc:      "nested objects",
+		obj:       []byte(`{"aaa":"foo", "bbb":{"ccc":1, "ddd":{"eee":true}}, "fff":null}`),
+		firstKeys: []string{`bbb.ddd.eee`},
+		expected:  []byte(`{"bbb.ddd.eee":true,"aaa":"foo","bbb.ccc":1,"fff":null}`),
+	},
+	{
+		desc:     "empty object",
+		obj:      []byte(`{"aaa":{ }, "bbb":{"ccc":{}}}`),
+		expected: []byte(`{"aaa":{ },"bbb.ccc":{}}`),
+	},
+	{
+		desc:      "separator",
+		obj:       []byte(`{"aaa":{"bbb":"foo"}}`),
+		flattener: Flattener{Separator: "_"},
+		expected:  []byte(`{"aaa_bbb":"foo"}`),
+	},
+	{
+		desc:      "arrays",
+		obj:       []byte(`{"aaa":[1, {"bbb":2}, [3]], "ccc":[]}`),
+		flattener: Flattener{IndexArrays: true},
+		expected:  []byte(`{"aaa.0":1,"aaa.1.bbb":2,"aaa.2.0":3,"ccc":[]}`),
+	},
+	{
+		desc:      "max depth",
+		obj:       []byte(`{"aaa":{"bbb":{"ccc":{"ddd":1}}}}`),
+		flattener: Flattener{MaxDepth: 2},
+		expected:  []byte(`{"aaa.bbb.ccc":{"ddd":1}}`),
+	},
+}
+
+func TestFlatten(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	for _, test := range flattenTests {
+		buf.Reset()
+		flattener := test.flattener
+		writer.FirstKeys = test.firstKeys
+		writer.Flatten = &flattener
+		_, err := writer.Write(test.obj)
+		if err != nil {
+			t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			continue
+		}
+		result := bytes.TrimRight(buf.Bytes(), "\n")
+		if !bytes.Equal(test.expected, result) {
+			t.Errorf("test \"%s\" unexpected: %s", test.desc, string(result))
+		}
+	}
+}
+
+func TestFlattenWriterMaxDepth(t *testing.T) {
+	// Flattener uses the Writer's limit, which can be above the default
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.MaxDepth = 2 * defaultMaxDepth
+	writer.Flatten = &Flattener{IndexArrays: true}
+	writer.Write(nested(defaultMaxDepth + 10))
+	if bytes.Count(buf.Bytes(), []byte(`[`)) != 0 || !bytes.HasSuffix(buf.Bytes(), []byte(`":1,"c":2}`+"\n")) {
+		t.Errorf("event wasn't fully flattened")
+	}
+}
@@ -40,13 +40,9 @@ func FuzzParser(f *testing.F) {
 			}
 			// as of go 1.15 the max nesting depth of the stdlib parser is 10000:
 			// https://github.com/golang/go/commit/84afaa9e9491d76ea43d7125b336030a0a2a902d
-			// so it will accept objects that the zord parser won't. if obj
-			// causes a depth limit error, ignore it
-			if errors.Is(err, errMaxDepth) {
-				t.Skip()
-			} else {
-				t.Fatal(err)
-			}
+			// which is also the zord parser's default, so depth limit errors
+			// aren't skipped
+			t.Fatal(err)
 		}
 		if stdlibErr != nil {
 			t.Fatal(errors.New("accepted invalid JSON"))
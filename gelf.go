@@ -0,0 +1,220 @@
+package zord
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// GELFTransformer is a Transformer that converts events into Graylog
+// Extended Log Format (GELF) 1.1 messages:
+//
+//	message -> short_message
+//	time    -> timestamp, in seconds since the unix epoch
+//	level   -> level, as a syslog severity
+//
+// host is set to Host and version to "1.1". All other fields become
+// additional fields, with their keys prefixed by "_" and any characters
+// that GELF doesn't allow in keys replaced by "_". Additional fields may only
+// be strings or numbers, so other values are replaced by strings of their
+// JSON encoding, except for nulls, which are dropped.
+//
+// In Writer.Transformers, the fields Writer adds afterwards (Fields,
+// ComputedFields, the truncation marker and the HashChain field) become
+// additional fields too. VerifyHashChain then needs the prefixed key of the
+// hash field, like "_hash".
+type GELFTransformer struct {
+	Host string // value of the host field. If "", os.Hostname() is used, looked up once
+}
+
+// Transform converts fields into a GELF message.
+func (g GELFTransformer) Transform(fields []Field) ([]Field, error) {
+	host := g.Host
+	if host == "" {
+		host = localHostname()
+	}
+	message := make([]Field, 0, len(fields)+2)
+	message = append(message, StringField("version", "1.1"), StringField("host", host))
+	hasMessage, hasTimestamp := false, false
+	for _, field := range fields {
+		switch field.Key {
+		case zerolog.MessageFieldName:
+			if isStringLiteral(field.Value) && !hasMessage {
+				message = append(message, Field{Key: "short_message", Value: field.Value})
+				hasMessage = true
+				continue
+			}
+		case zerolog.TimestampFieldName:
+			if ts, ok := (TimeNormalizer{}).parse(field.Value); ok && !hasTimestamp {
+				message = append(message, gelfTimestampField(ts))
+				hasTimestamp = true
+				continue
+			}
+		case zerolog.LevelFieldName:
+			message = append(message, Field{
+				Key:   "level",
+				Value: strconv.AppendInt(nil, int64(syslogSeverity(field.Value)), 10),
+			})
+			continue
+		}
+		if additional, ok := gelfAdditionalField(field); ok {
+			message = append(message, additional)
+		}
+	}
+	if !hasMessage {
+		// short_message is required
+		message = append(message, StringField("short_message", "-"))
+	}
+	if !hasTimestamp {
+		message = append(message, gelfTimestampField(time.Now()))
+	}
+	return message, nil
+}
+
+// addField adds field to the GELF message fields as an additional field.
+func (g GELFTransformer) addField(fields []Field, field Field) ([]Field, error) {
+	if additional, ok := gelfAdditionalField(field); ok {
+		return append(fields, additional), nil
+	}
+	return fields, nil
+}
+
+// gelfAdditionalField converts field into an additional field, or reports
+// false if it should be dropped
+func gelfAdditionalField(field Field) (Field, bool) {
+	if len(field.Value) == 0 {
+		return field, false
+	}
+	value := field.Value
+	switch value[0] {
+	case 'n':
+		return field, false
+	case 't', 'f', '{', '[':
+		value = jsonconv.QuoteBytes(value)
+	}
+	return Field{Key: gelfKey(field.Key), Value: value}, true
+}
+
+func gelfTimestampField(ts time.Time) Field {
+	value := strconv.AppendInt(nil, ts.Unix(), 10)
+	if ms := ts.Nanosecond() / int(time.Millisecond); ms > 0 {
+		value = append(value, '.')
+		value = append(value, '0'+byte(ms/100), '0'+byte(ms/10%10), '0'+byte(ms%10))
+	}
+	return Field{Key: "timestamp", Value: value}
+}
+
+// gelfKey returns key as an additional field key: prefixed with "_" and
+// restricted to word characters, dots and dashes. "_id" is reserved.
+func gelfKey(key string) string {
+	b := make([]byte, 0, len(key)+2)
+	b = append(b, '_')
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_', c == '.', c == '-':
+			b = append(b, c)
+		default:
+			b = append(b, '_')
+		}
+	}
+	if string(b) == "_id" {
+		return "__id"
+	}
+	return string(b)
+}
+
+// NewGELFWriter creates a new Writer that writes GELF 1.1 messages using
+// GELFTransformer. The output writer is os.Stderr; use a GELFUDPWriter to
+// send the messages to Graylog.
+func NewGELFWriter() *Writer {
+	w := NewWriter()
+	w.FirstKeys = []string{"version", "host", "short_message", "timestamp", "level"}
+	w.Transformers = []Transformer{GELFTransformer{}}
+	return w
+}
+
+const (
+	gelfChunkHeaderSize = 12
+	gelfMaxChunks       = 128
+	// DefaultGELFChunkSize is the default maximum size of the UDP datagrams
+	// sent by GELFUDPWriter.
+	DefaultGELFChunkSize = 1420
+)
+
+var errGELFTooLarge = errors.New("gelf: message requires too many chunks")
+
+// GELFUDPWriter sends each Write as a GELF message over UDP, splitting
+// messages larger than ChunkSize into chunks.
+type GELFUDPWriter struct {
+	ChunkSize int // maximum datagram size. If 0, DefaultGELFChunkSize is used
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewGELFUDPWriter creates a GELFUDPWriter that sends messages to addr.
+func NewGELFUDPWriter(addr string) (*GELFUDPWriter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &GELFUDPWriter{conn: conn}, nil
+}
+
+// Write sends message, without any trailing newline.
+func (g *GELFUDPWriter) Write(message []byte) (n int, err error) {
+	n = len(message)
+	for len(message) > 0 && message[len(message)-1] == '\n' {
+		message = message[:len(message)-1]
+	}
+	if len(message) == 0 {
+		return n, nil
+	}
+	chunkSize := g.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultGELFChunkSize
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(message) <= chunkSize {
+		_, err = g.conn.Write(message)
+		return n, err
+	}
+	dataSize := chunkSize - gelfChunkHeaderSize
+	if dataSize <= 0 {
+		return 0, errGELFTooLarge
+	}
+	numChunks := (len(message) + dataSize - 1) / dataSize
+	if numChunks > gelfMaxChunks {
+		return 0, errGELFTooLarge
+	}
+	chunk := make([]byte, chunkSize)
+	chunk[0], chunk[1] = 0x1e, 0x0f
+	if _, err = rand.Read(chunk[2:10]); err != nil {
+		return 0, err
+	}
+	for i := 0; i < numChunks; i++ {
+		chunk[10], chunk[11] = byte(i), byte(numChunks)
+		data := message[i*dataSize:]
+		if len(data) > dataSize {
+			data = data[:dataSize]
+		}
+		size := gelfChunkHeaderSize + copy(chunk[gelfChunkHeaderSize:], data)
+		if _, err = g.conn.Write(chunk[:size]); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// Close closes the UDP connection.
+func (g *GELFUDPWriter) Close() error {
+	return g.conn.Close()
+}
@@ -0,0 +1,130 @@
+package zord
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type gelfTest struct {
+	desc     string
+	obj      []byte
+	expected []byte
+}
+
+var gelfTests = []gelfTest{
+	{
+		desc:     "standard fields",
+		obj:      []byte(`{"level":"warn","time":"2006-01-02T15:04:05.25Z","message":"hello"}`),
+		expected: []byte(`{"version":"1.1","host":"example","short_message":"hello","timestamp":1136214245.250,"level":4}`),
+	},
+	{
+		desc:     "additional fields",
+		obj:      []byte(`{"time":1136214245,"id":"x","a b":1,"ok":true,"obj":{"c":null},"nothing":null,"message":"hi"}`),
+		expected: []byte(`{"version":"1.1","host":"example","short_message":"hi","timestamp":1136214245,"__id":"x","_a_b":1,"_ok":"true","_obj":"{\"c\":null}"}`),
+	},
+	{
+		desc:     "missing message",
+		obj:      []byte(`{"level":"fatal","time":1136214245}`),
+		expected: []byte(`{"version":"1.1","host":"example","short_message":"-","timestamp":1136214245,"level":0}`),
+	},
+}
+
+func TestGELFWriter(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewGELFWriter()
+	writer.Output = buf
+	writer.Transformers = []Transformer{GELFTransformer{Host: "example"}}
+	for _, test := range gelfTests {
+		buf.Reset()
+		_, err := writer.Write(test.obj)
+		if err != nil {
+			t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			continue
+		}
+		result := bytes.TrimRight(buf.Bytes(), "\n")
+		if !bytes.Equal(test.expected, result) {
+			t.Errorf("test \"%s\" unexpected: %s", test.desc, string(result))
+		}
+	}
+}
+
+func TestGELFWriterAddedFields(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewGELFWriter()
+	writer.Output = buf
+	writer.Transformers = []Transformer{GELFTransformer{Host: "ex"}}
+	writer.Fields = []Field{StringField("env", "prod")}
+	writer.ComputedFields = []ComputedField{SequenceField("seq")}
+	writer.MaxValueLength = 3
+	writer.HashChain = &HashChain{}
+	writer.Write([]byte(`{"time":1136214245,"message":"hi","a":"foobar"}`))
+	// GELF doesn't allow arrays, so the truncation marker is a string
+	expected := `{"version":"1.1","host":"ex","short_message":"hi","timestamp":1136214245,"_a":"foo","_env":"pro","_seq":1,"__truncated":"[\"_a\",\"_env\"]","_hash":"`
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+	if err := VerifyHashChain(bytes.NewReader(buf.Bytes()), "_hash", ""); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGELFUDPWriter(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	udpWriter, err := NewGELFUDPWriter(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udpWriter.Close()
+	udpWriter.ChunkSize = 100
+	writer := NewGELFWriter()
+	writer.Output = udpWriter
+
+	messages := []string{"short", strings.Repeat("long ", 100)}
+	for _, message := range messages {
+		obj, _ := json.Marshal(map[string]string{"message": message})
+		if _, err := writer.Write(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet := make([]byte, 2048)
+	for _, message := range messages {
+		var payload []byte
+		chunks := map[byte][]byte{}
+		for {
+			n, _, err := listener.ReadFrom(packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n > udpWriter.ChunkSize {
+				t.Fatalf("datagram too large: %d", n)
+			}
+			if packet[0] != 0x1e || packet[1] != 0x0f {
+				payload = append(payload, packet[:n]...)
+				break
+			}
+			chunks[packet[10]] = append([]byte(nil), packet[12:n]...)
+			if len(chunks) == int(packet[11]) {
+				for i := 0; i < len(chunks); i++ {
+					payload = append(payload, chunks[byte(i)]...)
+				}
+				break
+			}
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(payload, &decoded); err != nil {
+			t.Fatalf("invalid message: %v", err)
+		}
+		if decoded["short_message"] != message || decoded["version"] != "1.1" {
+			t.Errorf("unexpected message: %s", payload)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package zord
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/7fffffff/jsonconv"
+)
+
+// DefaultHashChainKey is the key of the hash field if HashChain.Key is "".
+const DefaultHashChainKey = "hash"
+
+// HashChain makes a log tamper-evident by adding a field to each event with
+// a hash that links it to the previous event. The hash is the hex encoded
+// SHA-256 of the event in canonical form (see Canonicalize), without the
+// hash field, followed by the hash of the previous event (or Seed, for the
+// first event). Since it's computed after all other transformations, any
+// change to a written event, or the removal or insertion of events, breaks
+// the chain. Use VerifyHashChain to check a log.
+//
+// Events that Writer writes as-is because they couldn't be parsed, or that
+// have duplicate keys, aren't chained, and VerifyHashChain reports them as
+// broken links. Writes to Output are serialized, so that events are written
+// in the order they're chained.
+//
+// A HashChain must not be shared by several Writers or copied after first
+// use.
+type HashChain struct {
+	Key  string // key of the hash field. If "", DefaultHashChainKey is used
+	Seed string // previous hash of the first event
+
+	mu   sync.Mutex
+	prev []byte
+	next []byte
+}
+
+func (h *HashChain) key() string {
+	if h.Key == "" {
+		return DefaultHashChainKey
+	}
+	return h.Key
+}
+
+// link adds the hash field to fields with add. The new hash becomes the
+// previous hash once commit is called. h.mu must be held.
+func (h *HashChain) link(fields []Field, add func([]Field, Field) ([]Field, error)) ([]Field, error) {
+	if h.prev == nil {
+		h.prev = []byte(h.Seed)
+	}
+	sum, err := hashEvent(fields, h.key(), h.prev)
+	if err != nil {
+		return fields, err
+	}
+	h.next = sum
+	return add(fields, Field{Key: h.key(), Value: jsonconv.QuoteBytes(sum)})
+}
+
+// commit makes the hash computed by the last call to link the previous hash,
+// after the event was written. h.mu must be held.
+func (h *HashChain) commit() {
+	if h.next != nil {
+		h.prev, h.next = h.next, nil
+	}
+}
+
+// hashEvent returns the hex encoded hash of the event made of fields,
+// ignoring fields named key, linked to prev
+func hashEvent(fields []Field, key string, prev []byte) ([]byte, error) {
+	hashed := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		if field.Key != key {
+			hashed = append(hashed, field)
+		}
+	}
+	canonical, err := appendCanonical(nil, hashed)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write(canonical)
+	h.Write(prev)
+	sum := make([]byte, hex.EncodedLen(sha256.Size))
+	hex.Encode(sum, h.Sum(nil))
+	return sum, nil
+}
+
+// BrokenLinkError is returned by VerifyHashChain for the first event whose
+// hash doesn't match.
+type BrokenLinkError struct {
+	Line   int    // line number of the event, starting at 1
+	Reason string // why the link is broken
+}
+
+func (e *BrokenLinkError) Error() string {
+	return "zord: hash chain broken at line " + strconv.Itoa(e.Line) + ": " + e.Reason
+}
+
+// VerifyHashChain reads events written by a Writer with a HashChain from r,
+// one per line, and checks that each event's hash, in the field named key,
+// links it to the previous event. seed is the previous hash of the first
+// event. If key is "", DefaultHashChainKey is used. Empty lines are skipped.
+// If an event has no field named key, the hash is looked for among its
+// attributes, as written by OTelTransformer.
+//
+// VerifyHashChain returns a *BrokenLinkError for the first event that isn't
+// correctly linked, or nil if all events are.
+func VerifyHashChain(r io.Reader, key, seed string) error {
+	if key == "" {
+		key = DefaultHashChainKey
+	}
+	prev := []byte(seed)
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		event, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(event)) > 0 {
+			hash, reason := verifyLink(event, key, prev)
+			if reason != "" {
+				return &BrokenLinkError{Line: line, Reason: reason}
+			}
+			prev = hash
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// verifyLink checks that event is linked to prev and returns its hash, or
+// the reason the link is broken.
+func verifyLink(event []byte, key string, prev []byte) (hash []byte, reason string) {
+	fields, err := ParseFields(event)
+	if err != nil {
+		return nil, "invalid event: " + err.Error()
+	}
+	for _, field := range fields {
+		if field.Key == key {
+			if hash != nil {
+				return nil, "duplicate hash field"
+			}
+			if s, ok := jsonconv.UnquoteBytes(field.Value); ok && isStringLiteral(field.Value) {
+				hash = s
+			} else {
+				return nil, "hash isn't a string"
+			}
+		}
+	}
+	if hash == nil {
+		// events written with OTelTransformer have the hash as an attribute
+		rest, value, ok := otelRemoveAttribute(fields, key)
+		if !ok {
+			return nil, "missing hash field"
+		}
+		if hash, ok = jsonconv.UnquoteBytes(value); !ok || !isStringLiteral(value) {
+			return nil, "hash isn't a string"
+		}
+		fields = rest
+	}
+	expected, err := hashEvent(fields, key, prev)
+	if err != nil {
+		return nil, "invalid event: " + err.Error()
+	}
+	if !bytes.Equal(hash, expected) {
+		return nil, "hash mismatch"
+	}
+	return expected, ""
+}
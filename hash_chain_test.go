@@ -0,0 +1,90 @@
+package zord
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func writeHashChain(t *testing.T, chain *HashChain, n int) []string {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.HashChain = chain
+	logger := zerolog.New(writer)
+	for i := 0; i < n; i++ {
+		logger.Info().Int("i", i).Msg("audit")
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+	return lines[:len(lines)-1]
+}
+
+func TestHashChain(t *testing.T) {
+	lines := writeHashChain(t, &HashChain{}, 3)
+	if len(lines) != 3 {
+		t.Fatalf("unexpected: %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], `{"level":"info","message":"audit","i":0,"hash":"`) {
+		t.Errorf("unexpected: %s", lines[0])
+	}
+	if err := VerifyHashChain(strings.NewReader(strings.Join(lines, "")), "", ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// seeds change every hash
+	seeded := writeHashChain(t, &HashChain{Key: "h", Seed: "abc"}, 3)
+	if err := VerifyHashChain(strings.NewReader(strings.Join(seeded, "")), "h", "abc"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := VerifyHashChain(strings.NewReader(strings.Join(seeded, "")), "h", ""); err == nil {
+		t.Error("expected error for wrong seed")
+	}
+
+	tests := []struct {
+		desc   string
+		log    string
+		line   int
+		reason string
+	}{
+		{"modified", lines[0] + strings.Replace(lines[1], `"i":1`, `"i":9`, 1) + lines[2], 2, "hash mismatch"},
+		{"reordered", lines[0] + lines[2] + lines[1], 2, "hash mismatch"},
+		{"removed", lines[0] + lines[2], 2, "hash mismatch"},
+		{"inserted", lines[0] + "{\"i\":5}\n" + lines[1], 2, "missing hash field"},
+		{"raw", lines[0] + lines[1] + "not json\n", 3, ""},
+	}
+	for _, test := range tests {
+		err := VerifyHashChain(strings.NewReader(test.log), "", "")
+		var brokenLink *BrokenLinkError
+		if !errors.As(err, &brokenLink) {
+			t.Errorf("test \"%s\" unexpected error: %v", test.desc, err)
+			continue
+		}
+		if brokenLink.Line != test.line || (test.reason != "" && brokenLink.Reason != test.reason) {
+			t.Errorf("test \"%s\" unexpected error: %v", test.desc, err)
+		}
+	}
+}
+
+func TestHashChainFormatting(t *testing.T) {
+	// the hash doesn't depend on key order or whitespace
+	lines := writeHashChain(t, &HashChain{}, 2)
+	reformatted := strings.Replace(lines[0], `{"level":"info","message":"audit",`, `{ "message": "audit", "level": "info", `, 1)
+	if err := VerifyHashChain(strings.NewReader(reformatted+lines[1]), "", ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHashChainInvalidStrings(t *testing.T) {
+	// lone surrogates would otherwise all hash like U+FFFD
+	for _, obj := range []string{`{"a":"\ud800"}`, `{"a":"\udc00"}`, `{"\ud800":1}`} {
+		fields, err := ParseFields([]byte(obj))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum, err := hashEvent(fields, DefaultHashChainKey, nil); err == nil {
+			t.Errorf("expected error for %s, got %s", obj, sum)
+		}
+	}
+}
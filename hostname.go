@@ -0,0 +1,20 @@
+package zord
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	hostnameOnce   sync.Once
+	cachedHostname string
+)
+
+// localHostname returns os.Hostname(), looked up once. It returns "" if
+// the hostname isn't available.
+func localHostname() string {
+	hostnameOnce.Do(func() {
+		cachedHostname, _ = os.Hostname()
+	})
+	return cachedHostname
+}
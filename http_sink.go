@@ -0,0 +1,342 @@
+package zord
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// HTTPFormat is the format of the batches sent by HTTPSink.
+type HTTPFormat int
+
+const (
+	// HTTPFormatNDJSON sends batches as newline delimited JSON.
+	HTTPFormatNDJSON HTTPFormat = iota
+	// HTTPFormatLoki sends batches in the Grafana Loki push API format.
+	HTTPFormatLoki
+)
+
+// defaults for HTTPSink
+const (
+	DefaultHTTPMaxBatchSize   = 100
+	DefaultHTTPMaxBatchAge    = time.Second
+	DefaultHTTPMaxRetries     = 5
+	DefaultHTTPInitialBackoff = 100 * time.Millisecond
+	DefaultHTTPMaxBackoff     = 10 * time.Second
+	DefaultHTTPQueueSize      = 16
+	DefaultHTTPTimeout        = 10 * time.Second
+)
+
+// defaultHTTPClient is used by HTTPSinks without a Client. Unlike
+// http.DefaultClient, it doesn't wait forever for an endpoint that hangs.
+var defaultHTTPClient = &http.Client{Timeout: DefaultHTTPTimeout}
+
+var errHTTPSinkClosed = errors.New("http sink: closed")
+
+// HTTPSink buffers the events written to it and POSTs them in batches to
+// URL. It's meant to be used as the Output of a Writer, which writes one
+// event per Write.
+//
+// A batch is sent when it has MaxBatchSize events or when its oldest event
+// is MaxBatchAge old, whichever comes first. Batches are sent in the
+// background, one at a time. Up to QueueSize batches wait to be sent; if the
+// queue is full, because the endpoint is slow or down, new batches are
+// dropped rather than blocking Write. If sending fails because of a network
+// error, a 429 or a 5xx response, it's retried up to MaxRetries times with
+// exponential backoff. Batches that are dropped or can't be sent are passed
+// to OnError as errors.
+//
+// In the Loki format, events are grouped into streams labelled with the
+// values of the fields named by LabelKeys, along with Labels. The
+// timestamp of each entry is taken from the event's timestamp field.
+//
+// The configuration fields must not be changed after the first Write. Close
+// sends any buffered events and waits for all batches to be sent.
+type HTTPSink struct {
+	URL            string
+	Format         HTTPFormat
+	LabelKeys      []string          // keys of fields used as Loki labels
+	Labels         map[string]string // static Loki labels
+	Header         http.Header       // additional request headers
+	Client         *http.Client      // if nil, a client with a DefaultHTTPTimeout timeout is used
+	MaxBatchSize   int               // maximum number of events per batch
+	MaxBatchAge    time.Duration     // maximum time an event waits to be sent
+	MaxRetries     int               // maximum number of retries per batch
+	InitialBackoff time.Duration     // delay before the first retry
+	MaxBackoff     time.Duration     // maximum delay between retries
+	QueueSize      int               // maximum number of batches waiting to be sent. If 0, DefaultHTTPQueueSize is used
+	OnError        func(error)       // called when a batch is dropped, if not nil
+
+	startOnce sync.Once
+	mu        sync.Mutex
+	batch     [][]byte
+	timer     *time.Timer
+	closed    bool
+	batches   chan [][]byte
+	done      chan struct{}
+}
+
+// NewHTTPSink creates a HTTPSink that sends NDJSON batches to url, with the
+// default limits.
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{
+		URL:            url,
+		MaxBatchSize:   DefaultHTTPMaxBatchSize,
+		MaxBatchAge:    DefaultHTTPMaxBatchAge,
+		MaxRetries:     DefaultHTTPMaxRetries,
+		InitialBackoff: DefaultHTTPInitialBackoff,
+		MaxBackoff:     DefaultHTTPMaxBackoff,
+		QueueSize:      DefaultHTTPQueueSize,
+	}
+}
+
+func (h *HTTPSink) start() {
+	queueSize := h.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultHTTPQueueSize
+	}
+	h.batches = make(chan [][]byte, queueSize)
+	h.done = make(chan struct{})
+	go h.send()
+}
+
+// Write adds a copy of event to the current batch.
+func (h *HTTPSink) Write(event []byte) (n int, err error) {
+	h.startOnce.Do(h.start)
+	n = len(event)
+	event = bytes.TrimRight(event, "\n")
+	if len(event) == 0 {
+		return n, nil
+	}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return 0, errHTTPSinkClosed
+	}
+	h.batch = append(h.batch, append([]byte(nil), event...))
+	if len(h.batch) == 1 && h.MaxBatchAge > 0 {
+		h.timer = time.AfterFunc(h.MaxBatchAge, h.flushTimer)
+	}
+	var dropped error
+	if h.MaxBatchSize <= 0 || len(h.batch) >= h.MaxBatchSize {
+		dropped = h.flushLocked()
+	}
+	h.mu.Unlock()
+	h.reportError(dropped)
+	return n, nil
+}
+
+func (h *HTTPSink) flushTimer() {
+	h.mu.Lock()
+	var dropped error
+	if !h.closed {
+		dropped = h.flushLocked()
+	}
+	h.mu.Unlock()
+	h.reportError(dropped)
+}
+
+// flushLocked hands the current batch to the sending goroutine without
+// blocking. If the queue is full, the batch is dropped and an error is
+// returned, to be reported once h.mu is released. h.mu must be held.
+func (h *HTTPSink) flushLocked() error {
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	if len(h.batch) == 0 {
+		return nil
+	}
+	batch := h.batch
+	h.batch = nil
+	select {
+	case h.batches <- batch:
+		return nil
+	default:
+		return fmt.Errorf("http sink: dropped %d events: queue full", len(batch))
+	}
+}
+
+func (h *HTTPSink) reportError(err error) {
+	if err != nil && h.OnError != nil {
+		h.OnError(err)
+	}
+}
+
+// Close sends any buffered events, waits for all batches to be sent and
+// stops the sending goroutine.
+func (h *HTTPSink) Close() error {
+	h.startOnce.Do(h.start)
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return errHTTPSinkClosed
+	}
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	batch := h.batch
+	h.batch = nil
+	h.closed = true
+	h.mu.Unlock()
+	// nothing else sends once closed is set, so wait for room in the queue
+	// for the last batch
+	if len(batch) > 0 {
+		h.batches <- batch
+	}
+	close(h.batches)
+	<-h.done
+	return nil
+}
+
+func (h *HTTPSink) send() {
+	defer close(h.done)
+	for batch := range h.batches {
+		h.reportError(h.sendBatch(batch))
+	}
+}
+
+func (h *HTTPSink) sendBatch(batch [][]byte) error {
+	var body []byte
+	contentType := "application/x-ndjson"
+	if h.Format == HTTPFormatLoki {
+		body = appendLokiPush(nil, batch, h.LabelKeys, h.Labels)
+		contentType = "application/json"
+	} else {
+		body = bytes.Join(batch, []byte("\n"))
+		body = append(body, '\n')
+	}
+	client := h.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	backoff := h.InitialBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = h.post(client, contentType, body)
+		if err == nil || !retry || attempt >= h.MaxRetries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if h.MaxBackoff > 0 && backoff > h.MaxBackoff {
+			backoff = h.MaxBackoff
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("http sink: dropped %d events: %w", len(batch), err)
+	}
+	return nil
+}
+
+// post sends a single request and reports whether it should be retried if
+// it failed
+func (h *HTTPSink) post(client *http.Client, contentType string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range h.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("unexpected status: %s", resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// appendLokiPush appends a Loki push request containing events to dest
+func appendLokiPush(dest []byte, events [][]byte, labelKeys []string, labels map[string]string) []byte {
+	type stream struct {
+		labels []byte
+		values []byte
+	}
+	var streams []*stream
+	byLabels := map[string]*stream{}
+	p := &parser{}
+	for _, event := range events {
+		eventLabels := make(map[string]string, len(labels)+len(labelKeys))
+		for key, value := range labels {
+			eventLabels[key] = value
+		}
+		ts := time.Time{}
+		if pairs, _, err := p.parse(event); err == nil {
+			for _, pair := range pairs {
+				if pair.keyUnquoted == zerolog.TimestampFieldName {
+					ts, _ = (TimeNormalizer{}).parse(pair.valueBytes)
+				}
+				for _, key := range labelKeys {
+					if pair.keyUnquoted == key {
+						eventLabels[key] = string(unquoteValue(pair.valueBytes))
+					}
+				}
+			}
+		}
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		streamLabels := appendLokiLabels(nil, eventLabels)
+		s, ok := byLabels[string(streamLabels)]
+		if !ok {
+			s = &stream{labels: streamLabels}
+			byLabels[string(streamLabels)] = s
+			streams = append(streams, s)
+		}
+		if len(s.values) > 0 {
+			s.values = append(s.values, ',')
+		}
+		s.values = append(s.values, `["`...)
+		s.values = strconv.AppendInt(s.values, ts.UnixNano(), 10)
+		s.values = append(s.values, `",`...)
+		s.values = jsonconv.AppendQuoteBytes(s.values, event)
+		s.values = append(s.values, ']')
+	}
+	dest = append(dest, `{"streams":[`...)
+	for i, s := range streams {
+		if i > 0 {
+			dest = append(dest, ',')
+		}
+		dest = append(dest, `{"stream":`...)
+		dest = append(dest, s.labels...)
+		dest = append(dest, `,"values":[`...)
+		dest = append(dest, s.values...)
+		dest = append(dest, `]}`...)
+	}
+	return append(dest, `]}`...)
+}
+
+// appendLokiLabels appends labels as a JSON object with sorted keys
+func appendLokiLabels(dest []byte, labels map[string]string) []byte {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	dest = append(dest, '{')
+	for i, key := range keys {
+		if i > 0 {
+			dest = append(dest, ',')
+		}
+		dest = jsonconv.AppendQuote(dest, key)
+		dest = append(dest, ':')
+		dest = jsonconv.AppendQuote(dest, labels[key])
+	}
+	return append(dest, '}')
+}
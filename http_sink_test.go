@@ -0,0 +1,194 @@
+package zord
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type httpSinkRecorder struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	types    []string
+	failures int // number of requests to fail before succeeding
+}
+
+func (r *httpSinkRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	r.bodies = append(r.bodies, body)
+	r.types = append(r.types, req.Header.Get("Content-Type"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *httpSinkRecorder) received() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]byte(nil), r.bodies...)
+}
+
+func TestHTTPSinkNDJSON(t *testing.T) {
+	recorder := &httpSinkRecorder{failures: 2}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	sink := NewHTTPSink(server.URL)
+	sink.MaxBatchSize = 2
+	sink.MaxBatchAge = time.Hour
+	sink.InitialBackoff = time.Millisecond
+	writer := NewWriter()
+	writer.Output = sink
+	writer.Write([]byte(`{"message":"a","level":"info"}`))
+	writer.Write([]byte(`{"message":"b"}`))
+	writer.Write([]byte(`{"message":"c"}`))
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"{\"level\":\"info\",\"message\":\"a\"}\n{\"message\":\"b\"}\n",
+		"{\"message\":\"c\"}\n",
+	}
+	received := recorder.received()
+	if len(received) != len(expected) {
+		t.Fatalf("unexpected number of batches: %d", len(received))
+	}
+	for i := range expected {
+		if string(received[i]) != expected[i] {
+			t.Errorf("batch #%d unexpected: %q", i, received[i])
+		}
+		if recorder.types[i] != "application/x-ndjson" {
+			t.Errorf("batch #%d unexpected content type: %s", i, recorder.types[i])
+		}
+	}
+	if _, err := sink.Write([]byte(`{}`)); err == nil {
+		t.Error("expected an error after Close")
+	}
+}
+
+func TestHTTPSinkMaxBatchAge(t *testing.T) {
+	recorder := &httpSinkRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	sink := NewHTTPSink(server.URL)
+	sink.MaxBatchAge = 10 * time.Millisecond
+	defer sink.Close()
+	sink.Write([]byte("{\"message\":\"a\"}\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("batch wasn't sent")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHTTPSinkRetries(t *testing.T) {
+	recorder := &httpSinkRecorder{failures: 3}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	var dropped error
+	sink := NewHTTPSink(server.URL)
+	sink.MaxRetries = 2
+	sink.InitialBackoff = time.Millisecond
+	sink.OnError = func(err error) {
+		dropped = err
+	}
+	sink.Write([]byte(`{"message":"a"}`))
+	sink.Close()
+	if dropped == nil || len(recorder.received()) != 0 {
+		t.Error("expected batch to be dropped")
+	}
+}
+
+func TestHTTPSinkLoki(t *testing.T) {
+	recorder := &httpSinkRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	sink := NewHTTPSink(server.URL)
+	sink.Format = HTTPFormatLoki
+	sink.LabelKeys = []string{"level", "service"}
+	sink.Labels = map[string]string{"env": "test"}
+	sink.Write([]byte(`{"time":1136214245,"level":"info","service":"greeter","message":"a"}`))
+	sink.Write([]byte(`{"time":1136214246,"level":"error","service":"greeter","message":"b"}`))
+	sink.Write([]byte(`{"time":1136214247,"level":"info","service":"greeter","message":"c"}`))
+	sink.Close()
+	received := recorder.received()
+	if len(received) != 1 || recorder.types[0] != "application/json" {
+		t.Fatalf("unexpected batches: %q", received)
+	}
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(received[0], &push); err != nil {
+		t.Fatal(err)
+	}
+	if len(push.Streams) != 2 {
+		t.Fatalf("unexpected streams: %s", received[0])
+	}
+	info, errors := push.Streams[0], push.Streams[1]
+	if info.Stream["level"] != "info" || info.Stream["service"] != "greeter" || info.Stream["env"] != "test" || len(info.Values) != 2 {
+		t.Errorf("unexpected stream: %+v", info)
+	}
+	if errors.Stream["level"] != "error" || len(errors.Values) != 1 {
+		t.Errorf("unexpected stream: %+v", errors)
+	}
+	if info.Values[1][0] != "1136214247000000000" || !bytes.Contains([]byte(info.Values[1][1]), []byte(`"message":"c"`)) {
+		t.Errorf("unexpected entry: %q", info.Values[1])
+	}
+}
+
+func TestHTTPSinkHangingServer(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	var mu sync.Mutex
+	var dropped []error
+	sink := NewHTTPSink(server.URL)
+	sink.Client = &http.Client{Timeout: 100 * time.Millisecond}
+	sink.MaxBatchSize = 1
+	sink.MaxRetries = 0
+	sink.QueueSize = 1
+	sink.OnError = func(err error) {
+		mu.Lock()
+		dropped = append(dropped, err)
+		mu.Unlock()
+	}
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if _, err := sink.Write([]byte(`{"message":"a"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Write blocked for %v", elapsed)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// at most two batches can be accepted: one being sent and one queued.
+	// they time out, so every batch is dropped
+	if len(dropped) != 10 {
+		t.Errorf("unexpected number of dropped batches: %d", len(dropped))
+	}
+}
@@ -0,0 +1,170 @@
+package zord
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultJournalSocket is the path of the socket journald listens on for
+// the native protocol.
+const DefaultJournalSocket = "/run/systemd/journal/socket"
+
+var errJournalClosed = errors.New("journal: writer is closed")
+
+// JournalWriter sends each event written to it to journald as structured
+// fields, using the native journal protocol:
+//
+//	message -> MESSAGE
+//	level   -> PRIORITY, as a syslog severity
+//	caller  -> CODE_FILE, CODE_LINE
+//
+// All other fields are upper-cased, with characters that journald doesn't
+// allow in field names replaced by '_'. String values are unquoted, other
+// values are sent in their JSON encoding. If the event can't be parsed, it's
+// sent as-is in MESSAGE.
+//
+// JournalWriter can be used as the output of a zerolog.Logger directly,
+// since the order of the fields doesn't matter to journald. Events must fit
+// in a single datagram.
+type JournalWriter struct {
+	Path       string // path of the journal socket. If "", DefaultJournalSocket is used
+	Identifier string // SYSLOG_IDENTIFIER of entries. If "", the name of the program is used
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+	buf    []byte
+}
+
+// NewJournalWriter creates a JournalWriter that sends entries to the local
+// journal. The connection is made on the first Write.
+func NewJournalWriter() *JournalWriter {
+	return &JournalWriter{}
+}
+
+// Write sends event to the journal as a single entry.
+func (j *JournalWriter) Write(event []byte) (n int, err error) {
+	n = len(event)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return 0, errJournalClosed
+	}
+	j.buf = j.appendEntry(j.buf[:0], bytes.TrimRight(event, "\n"))
+	for attempt := 0; attempt < 2; attempt++ {
+		if j.conn == nil {
+			path := j.Path
+			if path == "" {
+				path = DefaultJournalSocket
+			}
+			j.conn, err = net.Dial("unixgram", path)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if _, err = j.conn.Write(j.buf); err == nil {
+			return n, nil
+		}
+		j.conn.Close()
+		j.conn = nil
+	}
+	return 0, err
+}
+
+// Close closes the connection to the journal.
+func (j *JournalWriter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.closed = true
+	if j.conn == nil {
+		return nil
+	}
+	err := j.conn.Close()
+	j.conn = nil
+	return err
+}
+
+func (j *JournalWriter) appendEntry(dest, event []byte) []byte {
+	identifier := j.Identifier
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	dest = appendJournalField(dest, "SYSLOG_IDENTIFIER", []byte(identifier))
+	p := &parser{}
+	pairs, _, err := p.parse(event)
+	if err != nil {
+		return appendJournalField(dest, "MESSAGE", event)
+	}
+	for _, pair := range pairs {
+		switch pair.keyUnquoted {
+		case zerolog.MessageFieldName:
+			dest = appendJournalField(dest, "MESSAGE", unquoteValue(pair.valueBytes))
+			continue
+		case zerolog.LevelFieldName:
+			dest = appendJournalField(dest, "PRIORITY", strconv.AppendInt(nil, int64(syslogSeverity(pair.valueBytes)), 10))
+			continue
+		case zerolog.CallerFieldName:
+			if file, line, ok := splitCaller(pair.valueBytes); ok {
+				dest = appendJournalField(dest, "CODE_FILE", []byte(file))
+				dest = appendJournalField(dest, "CODE_LINE", strconv.AppendInt(nil, int64(line), 10))
+				continue
+			}
+		}
+		if name := journalFieldName(pair.keyUnquoted); name != "" {
+			dest = appendJournalField(dest, name, unquoteValue(pair.valueBytes))
+		}
+	}
+	return dest
+}
+
+// journalFieldName converts key into a valid journal field name: upper
+// case letters, digits and underscores, not starting with an underscore or
+// digit, and at most 64 characters long. It returns "" if there's nothing
+// left of key.
+func journalFieldName(key string) string {
+	name := make([]byte, 0, len(key))
+	for i := 0; i < len(key) && len(name) < 64; i++ {
+		c := key[i]
+		switch {
+		case 'a' <= c && c <= 'z':
+			c -= 'a' - 'A'
+		case 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9':
+			if len(name) == 0 {
+				continue
+			}
+		default:
+			if len(name) == 0 {
+				continue
+			}
+			c = '_'
+		}
+		name = append(name, c)
+	}
+	return string(name)
+}
+
+// appendJournalField appends a field in the native journal protocol format
+// to dest. Values containing newlines are length-prefixed.
+func appendJournalField(dest []byte, name string, value []byte) []byte {
+	dest = append(dest, name...)
+	if bytes.IndexByte(value, '\n') < 0 {
+		dest = append(dest, '=')
+		dest = append(dest, value...)
+		return append(dest, '\n')
+	}
+	dest = append(dest, '\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	dest = append(dest, size[:]...)
+	dest = append(dest, value...)
+	return append(dest, '\n')
+}
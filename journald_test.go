@@ -0,0 +1,98 @@
+package zord
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// parseJournalEntry decodes an entry in the native journal protocol format
+func parseJournalEntry(t *testing.T, entry []byte) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for len(entry) > 0 {
+		i := bytes.IndexAny(entry, "=\n")
+		if i < 0 {
+			t.Fatalf("invalid entry: %q", entry)
+		}
+		name := string(entry[:i])
+		if entry[i] == '=' {
+			end := bytes.IndexByte(entry, '\n')
+			fields[name] = string(entry[i+1 : end])
+			entry = entry[end+1:]
+			continue
+		}
+		entry = entry[i+1:]
+		size := int(binary.LittleEndian.Uint64(entry[:8]))
+		fields[name] = string(entry[8 : 8+size])
+		entry = entry[8+size+1:]
+	}
+	return fields
+}
+
+func TestJournalWriter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "zord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal.sock")
+	listener, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	writer := NewJournalWriter()
+	writer.Path = path
+	writer.Identifier = "zordtest"
+	defer writer.Close()
+	logger := zerolog.New(writer)
+	logger.Warn().
+		Str("caller", "/src/main.go:42").
+		Str("request-id", "abc").
+		Int("_count", 3).
+		Str("body", "line 1\nline 2").
+		Msg("hello")
+	writer.Write([]byte("not json\n"))
+
+	expected := []map[string]string{
+		{
+			"SYSLOG_IDENTIFIER": "zordtest",
+			"PRIORITY":          "4",
+			"CODE_FILE":         "/src/main.go",
+			"CODE_LINE":         "42",
+			"REQUEST_ID":        "abc",
+			"COUNT":             "3",
+			"BODY":              "line 1\nline 2",
+			"MESSAGE":           "hello",
+		},
+		{
+			"SYSLOG_IDENTIFIER": "zordtest",
+			"MESSAGE":           "not json",
+		},
+	}
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet := make([]byte, 4096)
+	for i, want := range expected {
+		n, _, err := listener.ReadFrom(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := parseJournalEntry(t, packet[:n])
+		if len(got) != len(want) {
+			t.Errorf("entry #%d unexpected: %q", i, got)
+			continue
+		}
+		for name, value := range want {
+			if got[name] != value {
+				t.Errorf("entry #%d unexpected %s: %q", i, name, got[name])
+			}
+		}
+	}
+}
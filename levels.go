@@ -0,0 +1,29 @@
+package zord
+
+import (
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// syslog severities for zerolog levels, matching zerolog.SyslogLevelWriter
+var syslogSeverities = map[string]int{
+	zerolog.DebugLevel.String(): 7, // debug
+	zerolog.InfoLevel.String():  6, // informational
+	zerolog.WarnLevel.String():  4, // warning
+	zerolog.ErrorLevel.String(): 3, // error
+	zerolog.FatalLevel.String(): 0, // emergency
+	zerolog.PanicLevel.String(): 2, // critical
+}
+
+// syslogSeverity returns the syslog severity for a JSON encoded zerolog
+// level. Unknown levels are informational, like zerolog.NoLevel.
+func syslogSeverity(value []byte) int {
+	if isStringLiteral(value) {
+		if level, ok := jsonconv.Unquote(value); ok {
+			if severity, ok := syslogSeverities[level]; ok {
+				return severity
+			}
+		}
+	}
+	return 6
+}
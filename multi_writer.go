@@ -0,0 +1,126 @@
+package zord
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// Format is the format of events written by a MultiWriter output.
+type Format int
+
+const (
+	JSONFormat    Format = iota // JSON objects, one per line
+	ConsoleFormat               // human readable lines, like zerolog.ConsoleWriter
+)
+
+// MultiOutput is one of the outputs of a MultiWriter.
+type MultiOutput struct {
+	Output       io.Writer                 // output writer
+	FirstKeys    []string                  // keys to be moved to the beginning of events
+	MinLevel     zerolog.Level             // events with lower levels are not written
+	Filter       func(fields []Field) bool // if not nil, only events for which Filter returns true are written
+	Transformers []Transformer             // transformers applied to events written to this output
+	Format       Format                    // output format
+	NoColor      bool                      // disables colors in ConsoleFormat
+}
+
+// MultiWriter writes events to several outputs, like io.MultiWriter, but
+// parses each event only once and lets each output have its own key order,
+// filters and format.
+//
+// An output that fails, by returning an error, panicking or having a
+// Transformer fail, doesn't stop the event from being written to the other
+// outputs. Write returns the first error after trying all outputs.
+//
+// If an event can't be parsed, it's written as-is to every output.
+type MultiWriter struct {
+	Outputs []MultiOutput
+}
+
+func (m MultiWriter) Write(event []byte) (n int, err error) {
+	p := &parser{}
+	pairs, n, parseErr := p.parse(event)
+	valid := parseErr == nil && skipWhitespace(event, n) == len(event)
+	var fields []Field
+	var level string
+	if valid {
+		fields = fieldsFromPairs(pairs)
+		for _, field := range fields {
+			if field.Key == zerolog.LevelFieldName {
+				level, _ = jsonconv.Unquote(field.Value)
+				break
+			}
+		}
+	}
+	for i := range m.Outputs {
+		var outputErr error
+		if valid {
+			outputErr = m.Outputs[i].tryWrite(fields, level, nil)
+		} else {
+			outputErr = m.Outputs[i].tryWrite(nil, "", event)
+		}
+		if outputErr != nil && err == nil {
+			err = outputErr
+		}
+	}
+	return len(event), err
+}
+
+// tryWrite writes fields to o, or raw as-is if it's not nil, recovering
+// from panics
+func (o MultiOutput) tryWrite(fields []Field, level string, raw []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if recoveredErr, ok := r.(error); ok {
+				err = fmt.Errorf("zord output panic: %w", recoveredErr)
+			} else {
+				err = fmt.Errorf("zord output panic: %v", r)
+			}
+		}
+	}()
+	if raw != nil {
+		_, err = o.Output.Write(raw)
+		return err
+	}
+	if !o.allows(level) {
+		return nil
+	}
+	if len(o.Transformers) > 0 || o.Filter != nil {
+		// transformers and filters may modify fields in place, so give them
+		// a copy for this output
+		fields = append(make([]Field, 0, len(fields)+4), fields...)
+	}
+	if o.Filter != nil && !o.Filter(fields) {
+		return nil
+	}
+	for _, t := range o.Transformers {
+		fields, err = t.Transform(fields)
+		if err != nil {
+			return err
+		}
+	}
+	var line []byte
+	switch o.Format {
+	case ConsoleFormat:
+		line = appendConsole(nil, fields, o.FirstKeys, !o.NoColor)
+	default:
+		line = reorderFields(nil, fields, o.FirstKeys)
+	}
+	line = append(line, '\n')
+	_, err = o.Output.Write(line)
+	return err
+}
+
+// allows reports whether events with the given level pass o.MinLevel.
+// Events without a known level always pass.
+func (o MultiOutput) allows(level string) bool {
+	for l := zerolog.DebugLevel; l <= zerolog.PanicLevel; l++ {
+		if l.String() == level {
+			return l >= o.MinLevel
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package zord
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("failed")
+}
+
+type panickingWriter struct{}
+
+func (panickingWriter) Write(p []byte) (int, error) {
+	panic("panicked")
+}
+
+func TestMultiWriter(t *testing.T) {
+	console := bytes.NewBuffer(nil)
+	file := bytes.NewBuffer(nil)
+	writer := MultiWriter{
+		Outputs: []MultiOutput{
+			{Output: failingWriter{}},
+			{Output: console, Format: ConsoleFormat, NoColor: true, FirstKeys: []string{"b"}},
+			{Output: panickingWriter{}},
+			{Output: file, FirstKeys: DefaultFirstKeys(), MinLevel: zerolog.InfoLevel},
+		},
+	}
+	events := []string{
+		`{"level":"debug","a":1,"b":"two words","time":1,"message":"hello"}`,
+		`{"level":"info","a":[1,2],"b":"x","message":"world"}`,
+		`{"a":1}`,
+		`{"a":1} trailing`,
+	}
+	for _, event := range events {
+		if _, err := writer.Write([]byte(event)); err == nil {
+			t.Errorf("expected error for %s", event)
+		}
+	}
+	expectedConsole := "1 |DEBU| hello b=\"two words\" a=1\n" +
+		"|INFO| world b=x a=[1,2]\n" +
+		"|????| a=1\n" +
+		`{"a":1} trailing`
+	if console.String() != expectedConsole {
+		t.Errorf("unexpected console output: %q", console.String())
+	}
+	expectedFile := "{\"level\":\"info\",\"message\":\"world\",\"a\":[1,2],\"b\":\"x\"}\n" +
+		"{\"a\":1}\n" +
+		`{"a":1} trailing`
+	if file.String() != expectedFile {
+		t.Errorf("unexpected file output: %q", file.String())
+	}
+}
+
+func TestMultiWriterFilter(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := MultiWriter{
+		Outputs: []MultiOutput{
+			{
+				Output: buf,
+				Filter: func(fields []Field) bool {
+					for _, field := range fields {
+						if field.Key == "audit" {
+							return true
+						}
+					}
+					return false
+				},
+				Transformers: []Transformer{TransformerFunc(func(fields []Field) ([]Field, error) {
+					return append(fields, StringField("output", "audit")), nil
+				})},
+			},
+		},
+	}
+	writer.Write([]byte(`{"message":"a"}`))
+	writer.Write([]byte(`{"message":"b","audit":"login"}`))
+	if buf.String() != "{\"message\":\"b\",\"audit\":\"login\",\"output\":\"audit\"}\n" {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+}
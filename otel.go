@@ -0,0 +1,307 @@
+package zord
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// OTelTransformer is a Transformer that converts events into the JSON
+// encoding of an OpenTelemetry (OTLP) LogRecord:
+//
+//	time    -> timeUnixNano
+//	level   -> severityNumber, severityText
+//	message -> body
+//
+// observedTimeUnixNano is set to the time of the conversion. The fields
+// named by TraceIDKey and SpanIDKey become traceId and spanId, and all other
+// fields become attributes, with their values encoded as AnyValue objects.
+//
+// In Writer.Transformers, the fields Writer adds afterwards (Fields,
+// ComputedFields, the truncation marker and the HashChain field) become
+// attributes too. VerifyHashChain finds the hash among the attributes.
+type OTelTransformer struct {
+	TraceIDKey string // key of the trace ID field. If "", "trace_id" is used
+	SpanIDKey  string // key of the span ID field. If "", "span_id" is used
+}
+
+// OTel severity numbers for zerolog levels
+var otelSeverities = map[string]int{
+	zerolog.DebugLevel.String(): 5,
+	zerolog.InfoLevel.String():  9,
+	zerolog.WarnLevel.String():  13,
+	zerolog.ErrorLevel.String(): 17,
+	zerolog.FatalLevel.String(): 21,
+	zerolog.PanicLevel.String(): 22,
+}
+
+// Transform converts fields into a LogRecord.
+func (o OTelTransformer) Transform(fields []Field) ([]Field, error) {
+	traceIDKey := o.TraceIDKey
+	if traceIDKey == "" {
+		traceIDKey = "trace_id"
+	}
+	spanIDKey := o.SpanIDKey
+	if spanIDKey == "" {
+		spanIDKey = "span_id"
+	}
+	record := make([]Field, 0, 8)
+	var attributes []byte
+	numAttributes := 0
+	var err error
+	for _, field := range fields {
+		switch field.Key {
+		case zerolog.TimestampFieldName:
+			if ts, ok := (TimeNormalizer{}).parse(field.Value); ok {
+				record = append(record, otelTimeField("timeUnixNano", ts))
+				continue
+			}
+		case zerolog.LevelFieldName:
+			if level, ok := jsonconv.Unquote(field.Value); ok && isStringLiteral(field.Value) {
+				if severity, ok := otelSeverities[level]; ok {
+					record = append(record, Field{Key: "severityNumber", Value: strconv.AppendInt(nil, int64(severity), 10)})
+				}
+				record = append(record, Field{Key: "severityText", Value: field.Value})
+				continue
+			}
+		case zerolog.MessageFieldName:
+			var body []byte
+			body, err = appendAnyValue(nil, field.Value)
+			if err != nil {
+				return fields, err
+			}
+			record = append(record, Field{Key: "body", Value: body})
+			continue
+		case traceIDKey:
+			if isStringLiteral(field.Value) {
+				record = append(record, Field{Key: "traceId", Value: field.Value})
+				continue
+			}
+		case spanIDKey:
+			if isStringLiteral(field.Value) {
+				record = append(record, Field{Key: "spanId", Value: field.Value})
+				continue
+			}
+		}
+		if numAttributes == 0 {
+			attributes = append(attributes, '[')
+		} else {
+			attributes = append(attributes, ',')
+		}
+		attributes, err = appendKeyValue(attributes, field.Key, field.Value)
+		if err != nil {
+			return fields, err
+		}
+		numAttributes++
+	}
+	record = append(record, otelTimeField("observedTimeUnixNano", time.Now()))
+	if numAttributes > 0 {
+		attributes = append(attributes, ']')
+		record = append(record, Field{Key: "attributes", Value: attributes})
+	}
+	return record, nil
+}
+
+// addField adds field to the LogRecord fields as an attribute.
+func (o OTelTransformer) addField(fields []Field, field Field) ([]Field, error) {
+	record := make([]Field, len(fields), len(fields)+1)
+	copy(record, fields)
+	for i := range record {
+		if record[i].Key != "attributes" {
+			continue
+		}
+		attributes := record[i].Value
+		attributes = append(attributes[:len(attributes)-1:len(attributes)-1], ',')
+		attributes, err := appendKeyValue(attributes, field.Key, field.Value)
+		if err != nil {
+			return fields, err
+		}
+		record[i].Value = append(attributes, ']')
+		return record, nil
+	}
+	attributes, err := appendKeyValue([]byte{'['}, field.Key, field.Value)
+	if err != nil {
+		return fields, err
+	}
+	return append(record, Field{Key: "attributes", Value: append(attributes, ']')}), nil
+}
+
+// otelRemoveAttribute returns fields without the attribute named key, and
+// its value if it's a string. VerifyHashChain uses it to find the hash of
+// LogRecords.
+func otelRemoveAttribute(fields []Field, key string) (rest []Field, value []byte, ok bool) {
+	for i, field := range fields {
+		if field.Key != "attributes" {
+			continue
+		}
+		p := &parser{}
+		elements, _, err := p.parseElements(field.Value)
+		if err != nil {
+			return fields, nil, false
+		}
+		kept := []byte{'['}
+		for _, element := range elements {
+			if value == nil {
+				if stringValue, found := otelStringAttribute(element, key); found {
+					value = stringValue
+					continue
+				}
+			}
+			if len(kept) > 1 {
+				kept = append(kept, ',')
+			}
+			kept = append(kept, element...)
+		}
+		if value == nil {
+			return fields, nil, false
+		}
+		rest = make([]Field, 0, len(fields))
+		rest = append(rest, fields[:i]...)
+		if len(kept) > 1 {
+			rest = append(rest, Field{Key: field.Key, Value: append(kept, ']')})
+		}
+		return append(rest, fields[i+1:]...), value, true
+	}
+	return fields, nil, false
+}
+
+// otelStringAttribute returns the string value of the KeyValue object
+// element if its key is key
+func otelStringAttribute(element []byte, key string) ([]byte, bool) {
+	p := &parser{}
+	pairs, _, err := p.parse(element)
+	if err != nil {
+		return nil, false
+	}
+	var found bool
+	var anyValue []byte
+	for _, pair := range pairs {
+		switch pair.keyUnquoted {
+		case "key":
+			k, ok := jsonconv.Unquote(pair.valueBytes)
+			found = ok && k == key
+		case "value":
+			anyValue = pair.valueBytes
+		}
+	}
+	if !found || anyValue == nil {
+		return nil, false
+	}
+	pairs, _, err = p.parse(anyValue)
+	if err != nil {
+		return nil, false
+	}
+	for _, pair := range pairs {
+		if pair.keyUnquoted == "stringValue" {
+			return pair.valueBytes, true
+		}
+	}
+	return nil, false
+}
+
+// otelTimeField returns a field with ts in nanoseconds since the unix epoch.
+// 64 bit integers are encoded as strings in OTLP/JSON.
+func otelTimeField(key string, ts time.Time) Field {
+	value := []byte{'"'}
+	value = strconv.AppendInt(value, ts.UnixNano(), 10)
+	return Field{Key: key, Value: append(value, '"')}
+}
+
+// appendKeyValue appends an OTLP KeyValue object to dest
+func appendKeyValue(dest []byte, key string, value []byte) ([]byte, error) {
+	dest = append(dest, `{"key":`...)
+	dest = jsonconv.AppendQuote(dest, key)
+	dest = append(dest, `,"value":`...)
+	dest, err := appendAnyValue(dest, value)
+	if err != nil {
+		return dest, err
+	}
+	return append(dest, '}'), nil
+}
+
+// appendAnyValue appends the OTLP AnyValue encoding of the JSON value to
+// dest
+func appendAnyValue(dest []byte, value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return append(dest, `{}`...), nil
+	}
+	if value[0] != '[' && value[0] != '{' {
+		return appendAnyNode(dest, []valueNode{{value: value}}, 0), nil
+	}
+	p := &parser{}
+	nodes, _, err := p.parseTree(0, value, 0)
+	if err != nil {
+		return dest, err
+	}
+	return appendAnyNode(dest, nodes, 0), nil
+}
+
+// appendAnyNode appends the OTLP AnyValue encoding of nodes[i] to dest
+func appendAnyNode(dest []byte, nodes []valueNode, i int) []byte {
+	value := nodes[i].value
+	switch value[0] {
+	case '"':
+		dest = append(dest, `{"stringValue":`...)
+		dest = append(dest, value...)
+	case 't', 'f':
+		dest = append(dest, `{"boolValue":`...)
+		dest = append(dest, value...)
+	case 'n':
+		return append(dest, `{}`...)
+	case '[':
+		dest = append(dest, `{"arrayValue":{"values":[`...)
+		for child := firstChild(nodes, i); child >= 0; child = nodes[child].next {
+			if child != i+1 {
+				dest = append(dest, ',')
+			}
+			dest = appendAnyNode(dest, nodes, child)
+		}
+		dest = append(dest, `]}`...)
+	case '{':
+		dest = append(dest, `{"kvlistValue":{"values":[`...)
+		for child := firstChild(nodes, i); child >= 0; child = nodes[child].next {
+			if child != i+1 {
+				dest = append(dest, ',')
+			}
+			key, _ := jsonconv.Unquote(nodes[child].key)
+			dest = append(dest, `{"key":`...)
+			dest = jsonconv.AppendQuote(dest, key)
+			dest = append(dest, `,"value":`...)
+			dest = appendAnyNode(dest, nodes, child)
+			dest = append(dest, '}')
+		}
+		dest = append(dest, `]}`...)
+	default:
+		if _, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			// 64 bit integers are encoded as strings in OTLP/JSON
+			dest = append(dest, `{"intValue":"`...)
+			dest = append(dest, value...)
+			dest = append(dest, '"')
+		} else {
+			dest = append(dest, `{"doubleValue":`...)
+			dest = append(dest, value...)
+		}
+	}
+	return append(dest, '}')
+}
+
+// NewOTelWriter creates a new Writer that writes events as OpenTelemetry
+// LogRecords, encoded as in OTLP/JSON, using OTelTransformer. The output
+// writer is os.Stderr.
+func NewOTelWriter() *Writer {
+	w := NewWriter()
+	w.FirstKeys = []string{
+		"timeUnixNano",
+		"observedTimeUnixNano",
+		"severityNumber",
+		"severityText",
+		"body",
+		"attributes",
+		"traceId",
+		"spanId",
+	}
+	w.Transformers = []Transformer{OTelTransformer{}}
+	return w
+}
@@ -0,0 +1,171 @@
+package zord
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// the parts of the OTLP/JSON logs data model used by OTelTransformer
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	ArrayValue  *struct {
+		Values []otlpAnyValue `json:"values"`
+	} `json:"arrayValue,omitempty"`
+	KvlistValue *struct {
+		Values []otlpKeyValue `json:"values"`
+	} `json:"kvlistValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 *otlpAnyValue  `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes"`
+	TraceID              string         `json:"traceId"`
+	SpanID               string         `json:"spanId"`
+}
+
+type otlpExportLogsServiceRequest struct {
+	ResourceLogs []struct {
+		ScopeLogs []struct {
+			LogRecords []otlpLogRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+// newOTLPCollector starts a stand-in for an OTLP/HTTP collector that
+// strictly decodes log export requests
+func newOTLPCollector(t *testing.T, received *[]otlpLogRecord) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpExportLogsServiceRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			t.Errorf("collector: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, resourceLogs := range req.ResourceLogs {
+			for _, scopeLogs := range resourceLogs.ScopeLogs {
+				*received = append(*received, scopeLogs.LogRecords...)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestOTelWriter(t *testing.T) {
+	var received []otlpLogRecord
+	collector := newOTLPCollector(t, &received)
+	defer collector.Close()
+
+	buf := bytes.NewBuffer(nil)
+	writer := NewOTelWriter()
+	writer.Output = buf
+	_, err := writer.Write([]byte(`{"level":"warn","time":1136214245,"trace_id":"5b8efff798038103d269b633813fc60c","span_id":"eee19b7ec3c1b174","n":-3,"f":0.5,"ok":true,"tags":["a",1],"obj":{"x":null},"message":"hello"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[`)
+	request = append(request, bytes.TrimRight(buf.Bytes(), "\n")...)
+	request = append(request, `]}]}]}`...)
+	resp, err := http.Post(collector.URL+"/v1/logs", "application/json", bytes.NewReader(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(received) != 1 {
+		t.Fatalf("collector rejected: %s", buf.String())
+	}
+	record := received[0]
+	if record.TimeUnixNano != "1136214245000000000" {
+		t.Errorf("unexpected timeUnixNano: %s", record.TimeUnixNano)
+	}
+	if record.ObservedTimeUnixNano == "" {
+		t.Error("missing observedTimeUnixNano")
+	}
+	if record.SeverityNumber != 13 || record.SeverityText != "warn" {
+		t.Errorf("unexpected severity: %d %s", record.SeverityNumber, record.SeverityText)
+	}
+	if record.Body == nil || record.Body.StringValue == nil || *record.Body.StringValue != "hello" {
+		t.Error("unexpected body")
+	}
+	if record.TraceID != "5b8efff798038103d269b633813fc60c" || record.SpanID != "eee19b7ec3c1b174" {
+		t.Errorf("unexpected trace context: %s %s", record.TraceID, record.SpanID)
+	}
+	attributes := map[string]otlpAnyValue{}
+	for _, kv := range record.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	if len(attributes) != 5 {
+		t.Errorf("unexpected attributes: %d", len(attributes))
+	}
+	if v := attributes["n"].IntValue; v == nil || *v != "-3" {
+		t.Error("unexpected intValue")
+	}
+	if v := attributes["f"].DoubleValue; v == nil || *v != 0.5 {
+		t.Error("unexpected doubleValue")
+	}
+	if v := attributes["ok"].BoolValue; v == nil || !*v {
+		t.Error("unexpected boolValue")
+	}
+	if v := attributes["tags"].ArrayValue; v == nil || len(v.Values) != 2 || *v.Values[0].StringValue != "a" || *v.Values[1].IntValue != "1" {
+		t.Error("unexpected arrayValue")
+	}
+	if v := attributes["obj"].KvlistValue; v == nil || len(v.Values) != 1 || v.Values[0].Key != "x" {
+		t.Error("unexpected kvlistValue")
+	}
+}
+
+func TestOTelWriterAddedFields(t *testing.T) {
+	var received []otlpLogRecord
+	collector := newOTLPCollector(t, &received)
+	defer collector.Close()
+
+	buf := bytes.NewBuffer(nil)
+	writer := NewOTelWriter()
+	writer.Output = buf
+	writer.Fields = []Field{StringField("env", "prod")}
+	writer.ComputedFields = []ComputedField{SequenceField("seq")}
+	writer.HashChain = &HashChain{}
+	writer.Write([]byte(`{"level":"info","time":1136214245,"message":"hello"}`))
+	writer.Write([]byte(`{"level":"info","time":1136214246,"a":1,"message":"hello"}`))
+	if err := VerifyHashChain(bytes.NewReader(buf.Bytes()), "", ""); err != nil {
+		t.Error(err)
+	}
+	tampered := bytes.Replace(buf.Bytes(), []byte(`"prod"`), []byte(`"test"`), 1)
+	if err := VerifyHashChain(bytes.NewReader(tampered), "", ""); err == nil {
+		t.Error("expected a broken link")
+	}
+	request := []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[`)
+	request = append(request, bytes.Replace(bytes.TrimRight(buf.Bytes(), "\n"), []byte("\n"), []byte(","), -1)...)
+	request = append(request, `]}]}]}`...)
+	resp, err := http.Post(collector.URL+"/v1/logs", "application/json", bytes.NewReader(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(received) != 2 {
+		t.Fatalf("collector rejected: %s", buf.String())
+	}
+	var keys []string
+	for _, kv := range received[1].Attributes {
+		keys = append(keys, kv.Key)
+	}
+	if len(keys) != 4 || keys[0] != "a" || keys[1] != "env" || keys[2] != "seq" || keys[3] != "hash" {
+		t.Errorf("unexpected attributes: %v", keys)
+	}
+}
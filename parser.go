@@ -4,12 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"unicode/utf8"
 
 	"github.com/7fffffff/jsonconv"
 )
 
 var (
-	errMaxDepth = errors.New("exceeded max depth")
+	errMaxDepth     = errors.New("exceeded max depth")
+	errTrailingData = errors.New("parse: unexpected data after object")
+	errDuplicateKey = errors.New("duplicate key")
+	errInvalidUTF8  = errors.New("invalid UTF-8")
+	errSurrogate    = errors.New("invalid surrogate pair")
 )
 
 type kv struct {
@@ -22,7 +27,9 @@ type kv struct {
 // JSON objects, and even then, only about finding the positions of the top
 // level key-value pairs within.
 type parser struct {
-	MaxDepth int // maximum nesting depth. If 0, defaultMaxDepth is used
+	MaxDepth            int  // maximum nesting depth. If 0, defaultMaxDepth is used
+	ValidateUTF8        bool // reject strings with invalid UTF-8 or lone surrogate escapes
+	RejectDuplicateKeys bool // reject objects with duplicate keys, at any depth
 }
 
 func (p *parser) depthLimitReached(depth int) bool {
@@ -40,6 +47,10 @@ func (p *parser) depthLimitReached(depth int) bool {
 // parse returns the key-value pairs and the number of bytes read from buf
 func (p *parser) parse(buf []byte) (pairs []kv, n int, err error) {
 	pairs = make([]kv, 0, 16)
+	var seen map[string]struct{}
+	if p.RejectDuplicateKeys {
+		seen = map[string]struct{}{}
+	}
 	n = skipWhitespace(buf, 0)
 	if n >= len(buf) {
 		return pairs, len(buf), parseErrorAt(n, fmt.Errorf("parse: %w", io.ErrUnexpectedEOF))
@@ -76,6 +87,12 @@ func (p *parser) parse(buf []byte) (pairs []kv, n int, err error) {
 		} else {
 			return pairs, n, parseErrorAt(keyStart, fmt.Errorf("parse: could not unquote key [%d:%d]", keyStart, n))
 		}
+		if p.RejectDuplicateKeys {
+			if _, ok := seen[pair.keyUnquoted]; ok {
+				return pairs, n, parseErrorAt(keyStart, fmt.Errorf("%w %s", errDuplicateKey, pair.keyBytes))
+			}
+			seen[pair.keyUnquoted] = struct{}{}
+		}
 		n = skipWhitespace(buf, n)
 		if n >= len(buf) {
 			return pairs, len(buf), parseErrorAt(n, fmt.Errorf("parse colon: %w", io.ErrUnexpectedEOF))
@@ -95,42 +112,43 @@ func (p *parser) parse(buf []byte) (pairs []kv, n int, err error) {
 	}
 }
 
-func (p *parser) parseArray(depth int, buf []byte, initialPos int) (end int, err error) {
-	i := initialPos
-	if i >= len(buf) {
-		return len(buf), parseErrorAt(i, fmt.Errorf("array: %w", io.ErrUnexpectedEOF))
-	}
-	if p.depthLimitReached(depth) {
-		return i + 1, parseErrorAt(i, fmt.Errorf("array: %w", errMaxDepth))
+// parseElements expects buf to contain a JSON array and extracts the
+// elements in the order they appear.
+//
+// parseElements returns the literal form of the elements and the number of
+// bytes read from buf
+func (p *parser) parseElements(buf []byte) (values [][]byte, n int, err error) {
+	values = make([][]byte, 0, 8)
+	n = skipWhitespace(buf, 0)
+	if n >= len(buf) {
+		return values, len(buf), parseErrorAt(n, fmt.Errorf("parse: %w", io.ErrUnexpectedEOF))
 	}
-	b := buf[i]
-	if b != '[' {
-		return i + 1, parseErrorAt(i, fmt.Errorf("array: unexpected 0x%X", b))
+	if b := buf[n]; b != '[' {
+		return values, n + 1, parseErrorAt(n, fmt.Errorf("parse: unexpected: 0x%X", b))
 	}
-	i++
-	numValues := 0
+	n++
 	for {
-		i = skipWhitespace(buf, i)
-		if i >= len(buf) {
-			return len(buf), parseErrorAt(i, fmt.Errorf("array: %w", io.ErrUnexpectedEOF))
+		n = skipWhitespace(buf, n)
+		if n >= len(buf) {
+			return values, len(buf), parseErrorAt(n, fmt.Errorf("parse: %w", io.ErrUnexpectedEOF))
 		}
-		b = buf[i]
+		b := buf[n]
 		if b == ']' {
-			return i + 1, nil
+			return values, n + 1, nil
 		}
-		if numValues > 0 {
+		if len(values) > 0 {
 			if b != ',' {
-				return i + 1, parseErrorAt(i, fmt.Errorf("array comma: unexpected 0x%X", b))
+				return values, n + 1, parseErrorAt(n, fmt.Errorf("parse comma: unexpected 0x%X", b))
 			}
-			i++
-			i = skipWhitespace(buf, i)
+			n++
+			n = skipWhitespace(buf, n)
 		}
-		valueEnd, err := p.parseValue(depth, buf, i)
+		valueStart := n
+		n, err = p.parseValue(0, buf, valueStart)
 		if err != nil {
-			return valueEnd, err
+			return values, n, err
 		}
-		i = valueEnd
-		numValues++
+		values = append(values, buf[valueStart:n])
 	}
 }
 
@@ -250,59 +268,6 @@ NumberExp:
 	return i, nil
 }
 
-func (p *parser) parseObject(depth int, buf []byte, initialPos int) (end int, err error) {
-	i := initialPos
-	if i >= len(buf) {
-		return len(buf), parseErrorAt(i, fmt.Errorf("object: %w", io.ErrUnexpectedEOF))
-	}
-	if p.depthLimitReached(depth) {
-		return i + 1, parseErrorAt(i, fmt.Errorf("object: %w", errMaxDepth))
-	}
-	b := buf[i]
-	if b != '{' {
-		return i + 1, parseErrorAt(i, fmt.Errorf("object: unexpected 0x%X", b))
-	}
-	i++
-	numPairs := 0
-	for {
-		i = skipWhitespace(buf, i)
-		if i >= len(buf) {
-			return len(buf), parseErrorAt(i, fmt.Errorf("object: %w", io.ErrUnexpectedEOF))
-		}
-		b = buf[i]
-		if b == '}' {
-			return i + 1, nil
-		}
-		if numPairs > 0 {
-			if b != ',' {
-				return i + 1, parseErrorAt(i, fmt.Errorf("object comma: unexpected 0x%X", b))
-			}
-			i++
-			i = skipWhitespace(buf, i)
-		}
-		keyEnd, err := p.parseString(buf, i)
-		if err != nil {
-			return keyEnd, err
-		}
-		i = skipWhitespace(buf, keyEnd)
-		if i >= len(buf) {
-			return len(buf), parseErrorAt(i, fmt.Errorf("object colon: %w", io.ErrUnexpectedEOF))
-		}
-		b = buf[i]
-		if b != ':' {
-			return i + 1, parseErrorAt(i, fmt.Errorf("object colon: unexpected 0x%X", b))
-		}
-		i++
-		i = skipWhitespace(buf, i)
-		valueEnd, err := p.parseValue(depth, buf, i)
-		if err != nil {
-			return valueEnd, fmt.Errorf("object value: %w", err)
-		}
-		i = valueEnd
-		numPairs++
-	}
-}
-
 func (p *parser) parseString(buf []byte, initialPos int) (end int, err error) {
 	i := initialPos
 	if i >= len(buf) {
@@ -335,6 +300,14 @@ func (p *parser) parseString(buf []byte, initialPos int) (end int, err error) {
 						break
 					}
 				}
+				if p.ValidateUTF8 && h == 4 {
+					escapeStart := i - 6
+					n, ok := surrogateEscapeLen(buf, escapeStart)
+					if !ok {
+						return i, parseErrorAt(escapeStart, fmt.Errorf("string: %w", errSurrogate))
+					}
+					i = escapeStart + n
+				}
 			default:
 				return i + 1, parseErrorAt(i, fmt.Errorf("string: unexpected 0x%X", b))
 			}
@@ -349,12 +322,37 @@ func (p *parser) parseString(buf []byte, initialPos int) (end int, err error) {
 			if b < ' ' {
 				return i + 1, parseErrorAt(i, fmt.Errorf("string: unexpected 0x%X", b))
 			}
+			if b >= utf8.RuneSelf && p.ValidateUTF8 {
+				r, size := utf8.DecodeRune(buf[i:])
+				if r == utf8.RuneError && size == 1 {
+					return i + 1, parseErrorAt(i, fmt.Errorf("string: %w", errInvalidUTF8))
+				}
+				i += size
+				continue
+			}
 		}
 		i++
 	}
 	return len(buf), parseErrorAt(i, fmt.Errorf("string: %w", io.ErrUnexpectedEOF))
 }
 
+// surrogateEscapeLen checks the \u escape at buf[i:]. If it's a high
+// surrogate followed by a low surrogate escape, it returns the length of
+// both, otherwise it returns 6. It reports false for lone surrogates.
+func surrogateEscapeLen(buf []byte, i int) (int, bool) {
+	r := getu4(buf[i+2 : i+6])
+	switch {
+	case utf16IsLowSurrogate(r):
+		return 6, false
+	case !utf16IsHighSurrogate(r):
+		return 6, true
+	}
+	if i+12 <= len(buf) && buf[i+6] == '\\' && buf[i+7] == 'u' && utf16IsLowSurrogate(getu4(buf[i+8:i+12])) {
+		return 12, true
+	}
+	return 6, false
+}
+
 func (p *parser) parseTrue(buf []byte, initialPos int) (end int, err error) {
 	raw := [4]byte{'t', 'r', 'u', 'e'}
 	for r := 0; r < len(raw); r++ {
@@ -370,7 +368,263 @@ func (p *parser) parseTrue(buf []byte, initialPos int) (end int, err error) {
 	return initialPos + len(raw), nil
 }
 
+// parseValue parses the value at buf[initialPos:]. depth is the nesting depth
+// of the value's container. Nested arrays and objects are parsed
+// iteratively, with an explicit stack of the open containers, so that
+// nesting is only limited by MaxDepth and memory use grows by one byte per
+// level.
 func (p *parser) parseValue(depth int, buf []byte, initialPos int) (end int, err error) {
+	i := initialPos
+	if i >= len(buf) {
+		return len(buf), parseErrorAt(len(buf), io.ErrUnexpectedEOF)
+	}
+	if b := buf[i]; b != '[' && b != '{' {
+		return p.parseScalar(buf, i)
+	}
+	var stack []byte               // '[' or '{' for each open container
+	var keys []map[string]struct{} // keys seen in each open container, if p.RejectDuplicateKeys
+	for {
+		// a value starts at i
+		if i >= len(buf) {
+			return len(buf), parseErrorAt(len(buf), io.ErrUnexpectedEOF)
+		}
+		b := buf[i]
+		if b == '[' || b == '{' {
+			kind := containerKind(b)
+			if p.depthLimitReached(depth + len(stack) + 1) {
+				return i + 1, parseErrorAt(i, fmt.Errorf("%s: %w", kind, errMaxDepth))
+			}
+			stack = append(stack, b)
+			if p.RejectDuplicateKeys {
+				keys = append(keys, nil)
+			}
+			i = skipWhitespace(buf, i+1)
+			if i >= len(buf) {
+				return len(buf), parseErrorAt(i, fmt.Errorf("%s: %w", kind, io.ErrUnexpectedEOF))
+			}
+			if buf[i] == containerEnd(b) {
+				stack = stack[:len(stack)-1]
+				if p.RejectDuplicateKeys {
+					keys = keys[:len(keys)-1]
+				}
+				i++
+			} else {
+				if b == '{' {
+					i, err = p.parseNestedKey(buf, i, keys)
+					if err != nil {
+						return i, err
+					}
+				}
+				continue
+			}
+		} else {
+			i, err = p.parseScalar(buf, i)
+			if err != nil {
+				if len(stack) > 0 && stack[len(stack)-1] == '{' {
+					return i, fmt.Errorf("object value: %w", err)
+				}
+				return i, err
+			}
+		}
+		// the value ended at i. close containers until another value is
+		// expected
+		for {
+			if len(stack) == 0 {
+				return i, nil
+			}
+			open := stack[len(stack)-1]
+			kind := containerKind(open)
+			i = skipWhitespace(buf, i)
+			if i >= len(buf) {
+				return len(buf), parseErrorAt(i, fmt.Errorf("%s: %w", kind, io.ErrUnexpectedEOF))
+			}
+			b = buf[i]
+			if b == containerEnd(open) {
+				stack = stack[:len(stack)-1]
+				if p.RejectDuplicateKeys {
+					keys = keys[:len(keys)-1]
+				}
+				i++
+				continue
+			}
+			if b != ',' {
+				return i + 1, parseErrorAt(i, fmt.Errorf("%s comma: unexpected 0x%X", kind, b))
+			}
+			i = skipWhitespace(buf, i+1)
+			if open == '{' {
+				i, err = p.parseNestedKey(buf, i, keys)
+				if err != nil {
+					return i, err
+				}
+			}
+			break
+		}
+	}
+}
+
+// valueNode is a value found by parseTree. The children of a container
+// follow it directly in the slice returned by parseTree, linked by next.
+type valueNode struct {
+	value    []byte // literal form (quoted/with brackets/etc)
+	key      []byte // double-quoted key literal, if the value is in an object
+	children int    // number of elements or members, if the value is a container
+	next     int    // index of the next element or member, or -1 if it's the last
+}
+
+// firstChild returns the index of the first child of nodes[i], or -1 if it
+// has none
+func firstChild(nodes []valueNode, i int) int {
+	if nodes[i].children == 0 {
+		return -1
+	}
+	return i + 1
+}
+
+// parseTree is like parseValue, but also returns every value nested in the
+// value at buf[initialPos:], in the order they appear, so that transforms
+// can walk nested values without parsing them once per level.
+func (p *parser) parseTree(depth int, buf []byte, initialPos int) (nodes []valueNode, end int, err error) {
+	i := initialPos
+	var stack []int  // index of each open container
+	var starts []int // start of each open container
+	var lastChild []int
+	var key []byte
+	for {
+		// a value starts at i
+		if i >= len(buf) {
+			return nodes, len(buf), parseErrorAt(len(buf), io.ErrUnexpectedEOF)
+		}
+		index := len(nodes)
+		nodes = append(nodes, valueNode{key: key, next: -1})
+		if len(stack) > 0 {
+			parent := len(stack) - 1
+			if lastChild[parent] >= 0 {
+				nodes[lastChild[parent]].next = index
+			}
+			lastChild[parent] = index
+			nodes[stack[parent]].children++
+		}
+		key = nil
+		b := buf[i]
+		if b == '[' || b == '{' {
+			kind := containerKind(b)
+			if p.depthLimitReached(depth + len(stack) + 1) {
+				return nodes, i + 1, parseErrorAt(i, fmt.Errorf("%s: %w", kind, errMaxDepth))
+			}
+			stack = append(stack, index)
+			starts = append(starts, i)
+			lastChild = append(lastChild, -1)
+			i = skipWhitespace(buf, i+1)
+			if i >= len(buf) {
+				return nodes, len(buf), parseErrorAt(i, fmt.Errorf("%s: %w", kind, io.ErrUnexpectedEOF))
+			}
+			if buf[i] != containerEnd(b) {
+				if b == '{' {
+					key, i, err = p.parseTreeKey(buf, i)
+					if err != nil {
+						return nodes, i, err
+					}
+				}
+				continue
+			}
+		} else {
+			start := i
+			i, err = p.parseScalar(buf, i)
+			if err != nil {
+				return nodes, i, err
+			}
+			nodes[index].value = buf[start:i]
+		}
+		// the value ended at i, or i is at the end of an empty container.
+		// close containers until another value is expected
+		for {
+			if len(stack) == 0 {
+				return nodes, i, nil
+			}
+			top := len(stack) - 1
+			open := buf[starts[top]]
+			kind := containerKind(open)
+			i = skipWhitespace(buf, i)
+			if i >= len(buf) {
+				return nodes, len(buf), parseErrorAt(i, fmt.Errorf("%s: %w", kind, io.ErrUnexpectedEOF))
+			}
+			b = buf[i]
+			if b == containerEnd(open) {
+				i++
+				nodes[stack[top]].value = buf[starts[top]:i]
+				stack, starts, lastChild = stack[:top], starts[:top], lastChild[:top]
+				continue
+			}
+			if b != ',' {
+				return nodes, i + 1, parseErrorAt(i, fmt.Errorf("%s comma: unexpected 0x%X", kind, b))
+			}
+			i = skipWhitespace(buf, i+1)
+			if open == '{' {
+				key, i, err = p.parseTreeKey(buf, i)
+				if err != nil {
+					return nodes, i, err
+				}
+			}
+			break
+		}
+	}
+}
+
+// parseTreeKey is like parseKey, but also returns the key literal
+func (p *parser) parseTreeKey(buf []byte, initialPos int) (key []byte, end int, err error) {
+	keyEnd, err := p.parseString(buf, initialPos)
+	if err != nil {
+		return nil, keyEnd, err
+	}
+	end, err = p.parseKey(buf, initialPos)
+	return buf[initialPos:keyEnd], end, err
+}
+
+// parseNestedKey is like parseKey, but if p.RejectDuplicateKeys is set, it
+// also records the key in the last of keys, the keys seen in each open
+// container, and returns an error if it was seen before
+func (p *parser) parseNestedKey(buf []byte, initialPos int, keys []map[string]struct{}) (end int, err error) {
+	end, err = p.parseKey(buf, initialPos)
+	if err != nil || !p.RejectDuplicateKeys {
+		return end, err
+	}
+	keyEnd, _ := p.parseString(buf, initialPos)
+	key, ok := jsonconv.Unquote(buf[initialPos:keyEnd])
+	if !ok {
+		return keyEnd, parseErrorAt(initialPos, fmt.Errorf("object: could not unquote key [%d:%d]", initialPos, keyEnd))
+	}
+	seen := keys[len(keys)-1]
+	if seen == nil {
+		seen = map[string]struct{}{}
+		keys[len(keys)-1] = seen
+	}
+	if _, ok := seen[key]; ok {
+		return keyEnd, parseErrorAt(initialPos, fmt.Errorf("%w %s", errDuplicateKey, buf[initialPos:keyEnd]))
+	}
+	seen[key] = struct{}{}
+	return end, nil
+}
+
+// parseKey parses an object key at buf[initialPos:] and the colon after it,
+// and returns the position of the value
+func (p *parser) parseKey(buf []byte, initialPos int) (end int, err error) {
+	keyEnd, err := p.parseString(buf, initialPos)
+	if err != nil {
+		return keyEnd, err
+	}
+	i := skipWhitespace(buf, keyEnd)
+	if i >= len(buf) {
+		return len(buf), parseErrorAt(i, fmt.Errorf("object colon: %w", io.ErrUnexpectedEOF))
+	}
+	if b := buf[i]; b != ':' {
+		return i + 1, parseErrorAt(i, fmt.Errorf("object colon: unexpected 0x%X", b))
+	}
+	return skipWhitespace(buf, i+1), nil
+}
+
+// parseScalar parses the string, number, boolean or null at
+// buf[initialPos:]
+func (p *parser) parseScalar(buf []byte, initialPos int) (end int, err error) {
 	i := initialPos
 	if i >= len(buf) {
 		return len(buf), parseErrorAt(len(buf), io.ErrUnexpectedEOF)
@@ -387,15 +641,26 @@ func (p *parser) parseValue(depth int, buf []byte, initialPos int) (end int, err
 		return p.parseFalse(buf, i)
 	case b == 'n':
 		return p.parseNull(buf, i)
-	case b == '[':
-		return p.parseArray(depth+1, buf, i)
-	case b == '{':
-		return p.parseObject(depth+1, buf, i)
 	default:
 		return i + 1, parseErrorAt(i, fmt.Errorf("value: unexpected: 0x%X", b))
 	}
 }
 
+// containerEnd returns the byte that closes the container opened by b
+func containerEnd(b byte) byte {
+	if b == '{' {
+		return '}'
+	}
+	return ']'
+}
+
+func containerKind(b byte) string {
+	if b == '{' {
+		return "object"
+	}
+	return "array"
+}
+
 // the fastest method
 // https://dave.cheney.net/high-performance-json.html
 var whitespace = [256]bool{
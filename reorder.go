@@ -59,3 +59,45 @@ func reorder(dest, src []byte, firstKeys []string) ([]byte, int, error) {
 	dest = append(dest, '}')
 	return dest, n, nil
 }
+
+// reorderFields is like reorder, but for fields that a Writer may have
+// transformed. It appends an object made of fields to dest, with the fields
+// named in firstKeys moved to the beginning, and returns the extended dest.
+func reorderFields(dest []byte, fields []Field, firstKeys []string) []byte {
+	keyPositions := map[string][]int{}
+	for i, field := range fields {
+		keyPositions[field.Key] = append(keyPositions[field.Key], i)
+	}
+	pairsWritten := 0
+	skip := map[int]struct{}{}
+	dest = append(dest, '{')
+	for _, key := range firstKeys {
+		for _, i := range keyPositions[key] {
+			if _, ok := skip[i]; ok {
+				continue
+			}
+			if pairsWritten > 0 {
+				dest = append(dest, ',')
+			}
+			dest = fields[i].appendKey(dest)
+			dest = append(dest, ':')
+			dest = append(dest, fields[i].Value...)
+			skip[i] = struct{}{}
+			pairsWritten++
+		}
+	}
+	for i, field := range fields {
+		if _, ok := skip[i]; ok {
+			continue
+		}
+		if pairsWritten > 0 {
+			dest = append(dest, ',')
+		}
+		dest = field.appendKey(dest)
+		dest = append(dest, ':')
+		dest = append(dest, field.Value...)
+		pairsWritten++
+	}
+	dest = append(dest, '}')
+	return dest
+}
@@ -2,6 +2,7 @@ package zord
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"testing"
@@ -454,3 +455,76 @@ func TestReorder(t *testing.T) {
 		}
 	}
 }
+
+func nested(depth int) []byte {
+	obj := make([]byte, 0, 2*depth+16)
+	obj = append(obj, `{"a":`...)
+	obj = append(obj, bytes.Repeat([]byte(`[{"b":`), depth/2)...)
+	obj = append(obj, bytes.Repeat([]byte(`[`), depth%2)...)
+	obj = append(obj, '1')
+	obj = append(obj, bytes.Repeat([]byte(`]`), depth%2)...)
+	obj = append(obj, bytes.Repeat([]byte(`}]`), depth/2)...)
+	return append(obj, `,"c":2}`...)
+}
+
+func TestParserDepth(t *testing.T) {
+	// nested(n) is n+1 levels deep, counting the top level object. the
+	// default limit matches encoding/json
+	if !json.Valid(nested(defaultMaxDepth-1)) || json.Valid(nested(defaultMaxDepth)) {
+		t.Error("default max depth doesn't match encoding/json")
+	}
+	p := &parser{}
+	if _, _, err := p.parse(nested(defaultMaxDepth - 1)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, _, err := p.parse(nested(defaultMaxDepth)); !errors.Is(err, errMaxDepth) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	p = &parser{MaxDepth: 3}
+	if _, _, err := p.parse(nested(2)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, _, err := p.parse(nested(3)); !errorIsAtFunc(errMaxDepth, 11)(err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWriterMaxDepth(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.FirstKeys = []string{"c"}
+	deep := nested(5000)
+	writer.Write(deep)
+	if !bytes.HasPrefix(buf.Bytes(), []byte(`{"c":2,"a":[{"b":[{`)) {
+		t.Errorf("deeply nested event wasn't reordered")
+	}
+	buf.Reset()
+	writer.MaxDepth = 3
+	writer.Write(nested(2))
+	writer.Write(nested(3))
+	expected := `{"c":2,"a":[{"b":1}]}` + "\n" + string(nested(3))
+	if buf.String() != expected {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+}
+
+func TestDeepTransforms(t *testing.T) {
+	// nested values are walked in one pass, not parsed again at each level
+	deep := nested(defaultMaxDepth - 1)
+	canonical, err := Canonicalize(deep)
+	if err != nil || !bytes.Equal(canonical, deep) {
+		t.Errorf("unexpected canonical form: %v", err)
+	}
+	fields, err := ParseFields(deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flattened, err := Flattener{IndexArrays: true}.Transform(fields)
+	if err != nil || len(flattened) != 2 || string(flattened[0].Value) != "1" {
+		t.Errorf("unexpected flattened fields: %v", err)
+	}
+	if _, err := appendAnyValue(nil, fields[0].Value); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
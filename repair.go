@@ -0,0 +1,45 @@
+package zord
+
+import "unicode/utf8"
+
+// repairUTF8 replaces invalid UTF-8 sequences in buf with U+FFFD, and lone
+// surrogate escapes with \ufffd. Since neither can appear outside of strings
+// in valid JSON, buf doesn't need to be parsed first. repairUTF8 returns buf
+// itself, and false, if nothing was replaced.
+func repairUTF8(buf []byte) ([]byte, bool) {
+	var repaired []byte
+	changed := false
+	start := 0
+	for i := 0; i < len(buf); {
+		b := buf[i]
+		switch {
+		case b == '\\' && i+6 <= len(buf) && buf[i+1] == 'u' && getu4(buf[i+2:i+6]) >= 0:
+			n, ok := surrogateEscapeLen(buf, i)
+			if !ok {
+				repaired = append(repaired, buf[start:i]...)
+				repaired = append(repaired, `\ufffd`...)
+				start = i + n
+				changed = true
+			}
+			i += n
+		case b == '\\':
+			// skip the escaped character, which may be a backslash
+			i += 2
+		case b < utf8.RuneSelf:
+			i++
+		default:
+			r, size := utf8.DecodeRune(buf[i:])
+			if r == utf8.RuneError && size == 1 {
+				repaired = append(repaired, buf[start:i]...)
+				repaired = append(repaired, "\uFFFD"...)
+				start = i + 1
+				changed = true
+			}
+			i += size
+		}
+	}
+	if !changed {
+		return buf, false
+	}
+	return append(repaired, buf[start:]...), true
+}
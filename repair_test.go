@@ -0,0 +1,73 @@
+package zord
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var utf8Tests = []struct {
+	desc     string
+	obj      string
+	valid    bool
+	pos      int
+	repaired string
+}{
+	{"ascii", `{"a":"b"}`, true, 0, `{"a":"b"}`},
+	{"multibyte", `{"é":"日本"}`, true, 0, `{"é":"日本"}`},
+	{"surrogate pair", `{"a":"\ud83d\ude00"}`, true, 0, `{"a":"\ud83d\ude00"}`},
+	{"escaped backslash", `{"a":"\\ud800"}`, true, 0, `{"a":"\\ud800"}`},
+	{"invalid byte", "{\"a\":\"x\xffy\"}", false, 7, "{\"a\":\"x\uFFFDy\"}"},
+	{"invalid key", "{\"\xc3\":1}", false, 2, "{\"\uFFFD\":1}"},
+	{"truncated rune", "{\"a\":\"\xe6\x97\"}", false, 6, "{\"a\":\"\uFFFD\uFFFD\"}"},
+	{"lone high surrogate", `{"a":"\ud83dx"}`, false, 6, `{"a":"\ufffdx"}`},
+	{"lone low surrogate", `{"a":"\ude00\ud83d\ude00"}`, false, 6, `{"a":"\ufffd\ud83d\ude00"}`},
+	{"reversed pair", `{"a":"\ude00\ud83d"}`, false, 6, `{"a":"\ufffd\ufffd"}`},
+	{"nested", `{"a":[{"b":"\udfff"}]}`, false, 12, `{"a":[{"b":"\ufffd"}]}`},
+}
+
+func TestParserValidateUTF8(t *testing.T) {
+	for _, test := range utf8Tests {
+		p := &parser{}
+		if _, _, err := p.parse([]byte(test.obj)); err != nil {
+			t.Errorf("test \"%s\" failed without validation: %v", test.desc, err)
+		}
+		p = &parser{ValidateUTF8: true}
+		_, _, err := p.parse([]byte(test.obj))
+		if test.valid {
+			if err != nil {
+				t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			}
+			continue
+		}
+		var posErr errorAt
+		if !errors.As(err, &posErr) || posErr.Pos() != test.pos {
+			t.Errorf("test \"%s\" unexpected error: %v", test.desc, err)
+		}
+		if !errors.Is(err, errInvalidUTF8) && !errors.Is(err, errSurrogate) {
+			t.Errorf("test \"%s\" unexpected error: %v", test.desc, err)
+		}
+	}
+}
+
+func TestWriterRepairUTF8(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.RepairUTF8 = true
+	writer.Strict = true
+	for _, test := range utf8Tests {
+		buf.Reset()
+		n, err := writer.Write([]byte(test.obj))
+		if err != nil {
+			t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			continue
+		}
+		if n != len(test.obj) {
+			t.Errorf("test \"%s\" unexpected n: %d", test.desc, n)
+		}
+		if result := bytes.TrimRight(buf.Bytes(), "\n"); string(result) != test.repaired {
+			t.Errorf("test \"%s\" unexpected: %s", test.desc, result)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package zord
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotatedTimeLayout = "20060102T150405.000000000"
+
+var errRotatingFileClosed = errors.New("rotating file: closed")
+
+// RotatingFile is an io.WriteCloser that writes to the file at Path, and
+// rotates it when it would grow larger than MaxSize bytes, when it's been
+// open for longer than Interval, or both. It's meant to be used as the
+// Output of a Writer: since Writer writes one event per Write, events are
+// never split across files.
+//
+// Rotated files are renamed to Path with the rotation time appended, and
+// are optionally compressed with gzip in the background. Only the MaxBackups
+// most recent rotated files are kept.
+type RotatingFile struct {
+	Path       string        // path of the file to write to
+	Mode       os.FileMode   // permissions for new files. If 0, 0644 is used
+	MaxSize    int64         // maximum size of the file in bytes, if > 0
+	Interval   time.Duration // maximum time before the file is rotated, if > 0
+	MaxBackups int           // maximum number of rotated files to keep, if > 0
+	Compress   bool          // whether to gzip rotated files
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+	wg       sync.WaitGroup
+	now      func() time.Time
+}
+
+// NewRotatingFile creates a RotatingFile that writes to path. The file is
+// opened on the first Write.
+func NewRotatingFile(path string) *RotatingFile {
+	return &RotatingFile{Path: path}
+}
+
+func (r *RotatingFile) timeNow() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// Write writes p to the file, rotating it first if needed. If p is larger
+// than MaxSize, it's written to a file of its own.
+func (r *RotatingFile) Write(p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, errRotatingFileClosed
+	}
+	if r.file == nil {
+		if err = r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.size > 0 && r.needsRotation(len(p)) {
+		if err = r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err = r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) needsRotation(writeSize int) bool {
+	if r.MaxSize > 0 && r.size+int64(writeSize) > r.MaxSize {
+		return true
+	}
+	if r.Interval > 0 && r.timeNow().Sub(r.openedAt) >= r.Interval {
+		return true
+	}
+	return false
+}
+
+// Rotate closes the current file and renames it, so that the next Write
+// starts a new file.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errRotatingFileClosed
+	}
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	return r.rotate()
+}
+
+// Close closes the file and waits for any background compression to
+// finish.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
+
+func (r *RotatingFile) open() error {
+	mode := r.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	file, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, mode)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = r.timeNow()
+	return nil
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	rotated := r.Path + "." + r.timeNow().UTC().Format(rotatedTimeLayout)
+	if err := os.Rename(r.Path, rotated); err != nil {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if r.Compress {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			if err := compressFile(rotated); err == nil {
+				os.Remove(rotated)
+			}
+			r.removeOldBackups()
+		}()
+		return nil
+	}
+	r.removeOldBackups()
+	return nil
+}
+
+// backups returns the paths of the rotated files, oldest first
+func (r *RotatingFile) backups() ([]string, error) {
+	dir, base := filepath.Split(r.Path)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := base + "."
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz")
+		if _, err := time.Parse(rotatedTimeLayout, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return strings.TrimSuffix(backups[i], ".gz") < strings.TrimSuffix(backups[j], ".gz")
+	})
+	return backups, nil
+}
+
+func (r *RotatingFile) removeOldBackups() {
+	if r.MaxBackups <= 0 {
+		return
+	}
+	backups, err := r.backups()
+	if err != nil {
+		return
+	}
+	// a file being compressed has two paths, so count by the uncompressed
+	// path
+	var rotations []string
+	for _, backup := range backups {
+		rotation := strings.TrimSuffix(backup, ".gz")
+		if len(rotations) == 0 || rotations[len(rotations)-1] != rotation {
+			rotations = append(rotations, rotation)
+		}
+	}
+	for len(rotations) > r.MaxBackups {
+		os.Remove(rotations[0])
+		os.Remove(rotations[0] + ".gz")
+		rotations = rotations[1:]
+	}
+}
+
+// compressFile writes a gzipped copy of path to path + ".gz"
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dest, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dest)
+	if _, err = io.Copy(gz, src); err != nil {
+		dest.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		dest.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	return dest.Close()
+}
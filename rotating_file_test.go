@@ -0,0 +1,128 @@
+package zord
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readBackups(t *testing.T, r *RotatingFile) [][]byte {
+	t.Helper()
+	backups, err := r.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents [][]byte
+	for _, backup := range backups {
+		data, err := os.ReadFile(backup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasSuffix(backup, ".gz") {
+			gz, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err = io.ReadAll(gz)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		contents = append(contents, data)
+	}
+	return contents
+}
+
+func newTestRotatingFile(t *testing.T) (*RotatingFile, func()) {
+	dir, err := os.MkdirTemp("", "zord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	r := NewRotatingFile(filepath.Join(dir, "app.log"))
+	r.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	return r, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	r, cleanup := newTestRotatingFile(t)
+	defer cleanup()
+	r.MaxSize = 50
+	writer := NewWriter()
+	writer.Output = r
+	for i := 0; i < 10; i++ {
+		writer.Write([]byte(fmt.Sprintf(`{"message":"event %d"}`, i)))
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile(r.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := append(readBackups(t, r), current)
+	if len(files) != 5 {
+		t.Fatalf("unexpected number of files: %d", len(files))
+	}
+	i := 0
+	for _, file := range files {
+		if len(file) > 50 {
+			t.Errorf("file too large: %d", len(file))
+		}
+		for _, line := range strings.SplitAfter(string(file), "\n") {
+			if line == "" {
+				continue
+			}
+			if expected := fmt.Sprintf("{\"message\":\"event %d\"}\n", i); line != expected {
+				t.Errorf("unexpected line: %q", line)
+			}
+			i++
+		}
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	r, cleanup := newTestRotatingFile(t)
+	defer cleanup()
+	r.Interval = 3 * time.Millisecond
+	r.MaxBackups = 2
+	r.Compress = true
+	for i := 0; i < 11; i++ {
+		r.Write([]byte(fmt.Sprintf("%d\n", i)))
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	backups := readBackups(t, r)
+	if len(backups) != 2 {
+		t.Fatalf("unexpected number of backups: %d", len(backups))
+	}
+	if string(backups[0]) != "3\n4\n5\n" || string(backups[1]) != "6\n7\n8\n" {
+		t.Errorf("unexpected backups: %q", backups)
+	}
+	for _, backup := range mustBackups(t, r) {
+		if !strings.HasSuffix(backup, ".gz") {
+			t.Errorf("backup not compressed: %s", backup)
+		}
+	}
+}
+
+func mustBackups(t *testing.T, r *RotatingFile) []string {
+	t.Helper()
+	backups, err := r.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backups
+}
@@ -0,0 +1,294 @@
+package zord
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// DefaultSamplerKeys returns the keys Sampler uses to group events by
+// default: the message and the caller.
+func DefaultSamplerKeys() []string {
+	return []string{
+		zerolog.MessageFieldName,
+		zerolog.CallerFieldName,
+	}
+}
+
+// Sampler limits the number of events that Writer writes. Events are
+// grouped by the values of the fields named by Keys, and each group is rate
+// limited by a token bucket that allows Rate events per second, with bursts
+// of up to Burst events. Events can also be sampled by level, with only 1 in
+// every LevelN[level] events written.
+//
+// If SummaryInterval is greater than 0, Writer writes a summary event
+// reporting how many events were suppressed in each group, at most once per
+// SummaryInterval. The summary is written by a timer once the interval has
+// passed, or before the next event if that comes first. Flush writes it
+// immediately. If the Sampler is shared, summaries are written by the
+// Writer that last wrote an event. Writes by the timer are serialized with
+// the writes of the Writers using the Sampler, so Output doesn't need to be
+// safe for concurrent use unless it's also written to by other means.
+//
+// A Sampler can be shared by several Writers, and must not be copied after
+// first use.
+type Sampler struct {
+	Keys            []string       // keys used to group events. If nil, DefaultSamplerKeys() is used
+	Rate            float64        // events per second allowed per group, if > 0
+	Burst           int            // maximum burst per group. If 0, the burst is the same as Rate (at least 1)
+	LevelN          map[string]int // write 1 in every N events of each level
+	SummaryInterval time.Duration  // minimum time between summary events, if > 0
+
+	writeMu     sync.Mutex // held while writing, including by the timer
+	mu          sync.Mutex
+	groups      map[string]*samplerGroup
+	levelCounts map[string]uint64
+	lastSummary time.Time
+	timer       *time.Timer
+	timerSet    bool
+	writeFn     func([]byte) (int, error)
+	now         func() time.Time
+}
+
+type samplerGroup struct {
+	fields     []Field // the fields that identify the group
+	tokens     float64
+	updated    time.Time
+	suppressed uint64
+}
+
+func (s *Sampler) timeNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// allow reports whether event should be written, and counts it as
+// suppressed if it shouldn't. Events that can't be parsed are allowed.
+// Summaries are written with writeFn.
+func (s *Sampler) allow(event []byte, writeFn func([]byte) (int, error)) bool {
+	p := &parser{}
+	pairs, _, err := p.parse(event)
+	if err != nil {
+		return true
+	}
+	keys := s.Keys
+	if keys == nil {
+		keys = DefaultSamplerKeys()
+	}
+	groupFields := make([]Field, 0, len(keys))
+	groupKey := make([]byte, 0, 64)
+	var level string
+	for _, pair := range pairs {
+		if pair.keyUnquoted == zerolog.LevelFieldName {
+			level = string(unquoteValue(pair.valueBytes))
+		}
+	}
+	for _, key := range keys {
+		for _, pair := range pairs {
+			if pair.keyUnquoted == key {
+				groupFields = append(groupFields, Field{Key: key, Value: append([]byte(nil), pair.valueBytes...)})
+				groupKey = append(groupKey, pair.valueBytes...)
+				break
+			}
+		}
+		groupKey = append(groupKey, 0)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeFn = writeFn
+	now := s.timeNow()
+	if s.groups == nil {
+		s.groups = map[string]*samplerGroup{}
+		s.levelCounts = map[string]uint64{}
+		s.lastSummary = now
+	}
+	group, ok := s.groups[string(groupKey)]
+	if !ok {
+		if len(s.groups) >= maxSamplerGroups {
+			s.forgetIdleGroups(now)
+		}
+		group = &samplerGroup{
+			fields:  groupFields,
+			tokens:  float64(s.burst()),
+			updated: now,
+		}
+		s.groups[string(groupKey)] = group
+	}
+	if n := s.LevelN[level]; n > 1 {
+		count := s.levelCounts[level]
+		s.levelCounts[level] = count + 1
+		if count%uint64(n) != 0 {
+			group.suppressed++
+			s.scheduleSummary(now)
+			return false
+		}
+	}
+	if s.Rate > 0 {
+		group.tokens += now.Sub(group.updated).Seconds() * s.Rate
+		if burst := float64(s.burst()); group.tokens > burst {
+			group.tokens = burst
+		}
+		group.updated = now
+		if group.tokens < 1 {
+			group.suppressed++
+			s.scheduleSummary(now)
+			return false
+		}
+		group.tokens--
+	}
+	return true
+}
+
+// maxSamplerGroups is the number of groups a Sampler tracks before it
+// forgets idle groups
+const maxSamplerGroups = 10000
+
+// forgetIdleGroups removes groups that have nothing suppressed and whose
+// buckets are full again. s.mu must be held.
+func (s *Sampler) forgetIdleGroups(now time.Time) {
+	burst := float64(s.burst())
+	for key, group := range s.groups {
+		if group.suppressed > 0 {
+			continue
+		}
+		if s.Rate > 0 && group.tokens+now.Sub(group.updated).Seconds()*s.Rate < burst {
+			continue
+		}
+		delete(s.groups, key)
+	}
+}
+
+func (s *Sampler) burst() int {
+	if s.Burst > 0 {
+		return s.Burst
+	}
+	if s.Rate > 1 {
+		return int(s.Rate)
+	}
+	return 1
+}
+
+// summary returns a summary event if one is due, or nil.
+func (s *Sampler) summary() []byte {
+	if s.SummaryInterval <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.summaryLocked(s.timeNow(), false)
+}
+
+// Flush writes a summary event if any events were suppressed since the last
+// summary, without waiting for SummaryInterval to pass.
+func (s *Sampler) Flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	summary := s.summaryLocked(s.timeNow(), true)
+	writeFn := s.writeFn
+	s.mu.Unlock()
+	if summary == nil {
+		return nil
+	}
+	_, err := writeFn(summary)
+	return err
+}
+
+// scheduleSummary starts the timer that writes the next summary, if it
+// isn't already running. s.mu must be held.
+func (s *Sampler) scheduleSummary(now time.Time) {
+	if s.SummaryInterval <= 0 || s.timerSet {
+		return
+	}
+	delay := s.lastSummary.Add(s.SummaryInterval).Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	if s.timer == nil {
+		s.timer = time.AfterFunc(delay, s.timeout)
+	} else {
+		s.timer.Reset(delay)
+	}
+	s.timerSet = true
+}
+
+func (s *Sampler) timeout() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	s.timerSet = false
+	now := s.timeNow()
+	summary := s.summaryLocked(now, false)
+	if summary == nil && s.anySuppressed() {
+		// a summary was written before the next event in the meantime
+		s.scheduleSummary(now)
+	}
+	writeFn := s.writeFn
+	s.mu.Unlock()
+	if summary != nil {
+		writeFn(summary)
+	}
+}
+
+// anySuppressed reports whether any events were suppressed since the last
+// summary. s.mu must be held.
+func (s *Sampler) anySuppressed() bool {
+	for _, group := range s.groups {
+		if group.suppressed > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// summaryLocked returns a summary event if one is due, or force is set and
+// events were suppressed, or nil. s.mu must be held.
+func (s *Sampler) summaryLocked(now time.Time, force bool) []byte {
+	if s.groups == nil || (!force && now.Sub(s.lastSummary) < s.SummaryInterval) {
+		return nil
+	}
+	s.lastSummary = now
+	var suppressed []byte
+	var total uint64
+	keys := make([]string, 0, len(s.groups))
+	for key := range s.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		group := s.groups[key]
+		if group.suppressed == 0 {
+			continue
+		}
+		if total == 0 {
+			suppressed = append(suppressed, '[')
+		} else {
+			suppressed = append(suppressed, ',')
+		}
+		fields := make([]Field, 0, len(group.fields)+1)
+		fields = append(fields, group.fields...)
+		fields = append(fields, Field{Key: "count", Value: strconv.AppendUint(nil, group.suppressed, 10)})
+		suppressed = reorderFields(suppressed, fields, nil)
+		total += group.suppressed
+		group.suppressed = 0
+	}
+	s.forgetIdleGroups(now)
+	if total == 0 {
+		return nil
+	}
+	suppressed = append(suppressed, ']')
+	summary := []Field{
+		{Key: zerolog.LevelFieldName, Value: jsonconv.Quote(zerolog.WarnLevel.String())},
+		{Key: zerolog.TimestampFieldName, Value: appendTime(nil, now, zerolog.TimeFieldFormat)},
+		{Key: "suppressed", Value: suppressed},
+		StringField(zerolog.MessageFieldName, "zord: events suppressed by sampler"),
+	}
+	return reorderFields(nil, summary, nil)
+}
@@ -0,0 +1,142 @@
+package zord
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func newTestSampler(s *Sampler, clock *time.Time) *Sampler {
+	s.now = func() time.Time {
+		return *clock
+	}
+	return s
+}
+
+func TestSamplerRate(t *testing.T) {
+	clock := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Sampler = newTestSampler(&Sampler{Rate: 1, Burst: 2}, &clock)
+	for i := 0; i < 5; i++ {
+		writer.Write([]byte(`{"message":"noisy","caller":"a.go:1"}`))
+		writer.Write([]byte(`{"message":"noisy","caller":"b.go:2"}`))
+	}
+	clock = clock.Add(time.Second)
+	writer.Write([]byte(`{"message":"noisy","caller":"a.go:1"}`))
+	writer.Write([]byte(`{"message":"noisy","caller":"a.go:1"}`))
+	expected := strings.Repeat("{\"caller\":\"a.go:1\",\"message\":\"noisy\"}\n{\"caller\":\"b.go:2\",\"message\":\"noisy\"}\n", 2) +
+		"{\"caller\":\"a.go:1\",\"message\":\"noisy\"}\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+}
+
+func TestSamplerLevelN(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Sampler = &Sampler{LevelN: map[string]int{"debug": 3}}
+	logger := zerolog.New(writer)
+	for i := 0; i < 7; i++ {
+		logger.Debug().Int("i", i).Msg("")
+		logger.Info().Int("i", i).Msg("")
+	}
+	if n := strings.Count(buf.String(), `"debug"`); n != 3 {
+		t.Errorf("unexpected number of debug events: %d", n)
+	}
+	if n := strings.Count(buf.String(), `"info"`); n != 7 {
+		t.Errorf("unexpected number of info events: %d", n)
+	}
+}
+
+func TestSamplerSummary(t *testing.T) {
+	defer func(format string) {
+		zerolog.TimeFieldFormat = format
+	}(zerolog.TimeFieldFormat)
+	zerolog.TimeFieldFormat = ""
+	clock := time.Unix(1136214245, 0)
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Sampler = newTestSampler(&Sampler{Rate: 1, SummaryInterval: time.Minute}, &clock)
+	for i := 0; i < 4; i++ {
+		writer.Write([]byte(`{"message":"aaa"}`))
+		writer.Write([]byte(`{"message":"bbb","caller":"b.go:2"}`))
+	}
+	writer.Write([]byte(`{"message":"ccc"}`))
+	buf.Reset()
+	clock = clock.Add(time.Minute)
+	writer.Write([]byte(`{"message":"ddd"}`))
+	expected := `{"time":1136214305,"level":"warn","message":"zord: events suppressed by sampler","suppressed":[{"message":"aaa","count":3},{"message":"bbb","caller":"b.go:2","count":3}]}` + "\n" +
+		`{"message":"ddd"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+	buf.Reset()
+	clock = clock.Add(time.Minute)
+	writer.Write([]byte(`{"message":"ddd"}`))
+	if buf.String() != "{\"message\":\"ddd\"}\n" {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+}
+
+func TestSamplerSummaryTimer(t *testing.T) {
+	buf := &lockedBuffer{}
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Sampler = &Sampler{Rate: 1, SummaryInterval: 10 * time.Millisecond}
+	writer.Write([]byte(`{"message":"aaa"}`))
+	writer.Write([]byte(`{"message":"aaa"}`))
+	// no more events, so the summary is written by the timer
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), `"suppressed":[{"message":"aaa","count":1}]`) {
+		if time.Now().After(deadline) {
+			t.Fatalf("summary wasn't written: %s", buf.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSamplerFlush(t *testing.T) {
+	buf := &lockedBuffer{}
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Sampler = &Sampler{Rate: 1, SummaryInterval: time.Hour}
+	writer.Write([]byte(`{"message":"aaa"}`))
+	writer.Write([]byte(`{"message":"aaa"}`))
+	if err := writer.Sampler.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"suppressed":[{"message":"aaa","count":1}]`) {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+	// nothing was suppressed since
+	before := buf.String()
+	writer.Sampler.Flush()
+	if buf.String() != before {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+}
+
+func TestSamplerSummaryTimerRace(t *testing.T) {
+	// the timer's writes don't race with the Writer's, so a plain buffer
+	// can be the output
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Sampler = &Sampler{Rate: 1, SummaryInterval: time.Millisecond}
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		writer.Write([]byte(`{"message":"aaa"}`))
+		time.Sleep(100 * time.Microsecond)
+	}
+	writer.Sampler.Flush()
+	if !strings.Contains(buf.String(), `"suppressed"`) {
+		t.Errorf("no summary was written")
+	}
+}
@@ -0,0 +1,18 @@
+package zord
+
+// validateStrict returns an error for the problems that Writer rejects in
+// strict mode: event isn't a valid JSON object, is followed by anything but
+// whitespace, contains invalid UTF-8 or lone surrogate escapes, or has
+// duplicate keys in any object, or is nested more than maxDepth levels deep.
+// The error has the position of the problem in event.
+func validateStrict(event []byte, maxDepth int) error {
+	p := &parser{MaxDepth: maxDepth, ValidateUTF8: true, RejectDuplicateKeys: true}
+	_, n, err := p.parse(event)
+	if err != nil {
+		return err
+	}
+	if n = skipWhitespace(event, n); n < len(event) {
+		return parseErrorAt(n, errTrailingData)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package zord
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriterStrict(t *testing.T) {
+	tests := []struct {
+		desc string
+		obj  string
+		pos  int
+		err  error
+	}{
+		{"invalid json", `{"a":}`, 5, nil},
+		{"trailing data", `{"a":1} x`, 8, errTrailingData},
+		{"invalid utf-8", "{\"a\":\"\xff\"}", 6, errInvalidUTF8},
+		{"duplicate key", `{"a":1,"b":2,"a":3}`, 13, errDuplicateKey},
+		{"nested duplicate key", `{"a":[{"b":1,"b":2}]}`, 13, errDuplicateKey},
+	}
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Strict = true
+	for _, test := range tests {
+		n, err := writer.Write([]byte(test.obj))
+		if err == nil {
+			t.Errorf("test \"%s\" expected error", test.desc)
+			continue
+		}
+		var posErr errorAt
+		if !errors.As(err, &posErr) || posErr.Pos() != test.pos {
+			t.Errorf("test \"%s\" unexpected error: %v", test.desc, err)
+		}
+		if test.err != nil && !errors.Is(err, test.err) {
+			t.Errorf("test \"%s\" unexpected error: %v", test.desc, err)
+		}
+		if n != 0 {
+			t.Errorf("test \"%s\" unexpected n: %d", test.desc, n)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+
+	writer.Transformers = []Transformer{TransformerFunc(func(fields []Field) ([]Field, error) {
+		panic("transformer panic")
+	})}
+	if _, err := writer.Write([]byte(`{"a":1}`)); err == nil {
+		t.Error("expected error for transformer panic")
+	}
+	writer.Transformers = nil
+	if _, err := writer.Write([]byte(`{"a":{"b":1},"c":[{"b":1},{"b":2}]}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if buf.String() != "{\"a\":{\"b\":1},\"c\":[{\"b\":1},{\"b\":2}]}\n" {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
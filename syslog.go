@@ -0,0 +1,195 @@
+package zord
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// syslog facility codes
+const (
+	SyslogFacilityUser   = 1
+	SyslogFacilityDaemon = 3
+	SyslogFacilityLocal0 = 16
+)
+
+const syslogTimestampLayout = "2006-01-02T15:04:05.999999Z07:00"
+
+// DefaultSyslogTimeout is the default timeout of SyslogWriter connections
+// and writes.
+const DefaultSyslogTimeout = 10 * time.Second
+
+var errSyslogClosed = errors.New("syslog: writer is closed")
+
+// SyslogWriter sends each Write to a syslog server as the MSG of an RFC 5424
+// message. It's meant to be used as the Output of a Writer, which writes one
+// event per Write.
+//
+// The PRI of each message is derived from the event's level, as in
+// zerolog.SyslogLevelWriter, and the TIMESTAMP from its timestamp field (or
+// the current time, if the event doesn't have one).
+//
+// Over "tcp" and "unix" (stream) connections, messages are framed by octet
+// counting as described in RFC 6587. Over "udp" and "unixgram" connections,
+// each message is sent as a single datagram. If sending fails, SyslogWriter
+// reconnects and tries again once. Connecting and sending time out after
+// Timeout, so that an unresponsive server doesn't stall logging indefinitely.
+type SyslogWriter struct {
+	Network  string        // "udp", "tcp", "unix" or "unixgram"
+	Addr     string        // address of the syslog server, or path of the socket
+	Facility int           // syslog facility. If 0, SyslogFacilityUser is used
+	Hostname string        // HOSTNAME of messages. If "", os.Hostname() is used, looked up once
+	AppName  string        // APP-NAME of messages. If "", the name of the program is used
+	MsgID    string        // MSGID of messages. If "", the nil value ("-") is used
+	Timeout  time.Duration // timeout of connecting and of each write. If 0, DefaultSyslogTimeout is used
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+	buf    []byte
+}
+
+// NewSyslogWriter creates a SyslogWriter that sends messages to addr over
+// network. The connection is made on the first Write.
+func NewSyslogWriter(network, addr string) *SyslogWriter {
+	return &SyslogWriter{
+		Network: network,
+		Addr:    addr,
+	}
+}
+
+// Write sends event to the syslog server, without any trailing newline.
+func (s *SyslogWriter) Write(event []byte) (n int, err error) {
+	n = len(event)
+	for len(event) > 0 && event[len(event)-1] == '\n' {
+		event = event[:len(event)-1]
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, errSyslogClosed
+	}
+	s.buf = s.appendMessage(s.buf[:0], event)
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSyslogTimeout
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			s.conn, err = net.DialTimeout(s.Network, s.Addr, timeout)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if err = s.conn.SetWriteDeadline(time.Now().Add(timeout)); err == nil {
+			if _, err = s.conn.Write(s.buf); err == nil {
+				return n, nil
+			}
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return 0, err
+}
+
+// Close closes the connection to the syslog server.
+func (s *SyslogWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SyslogWriter) framed() bool {
+	return s.Network == "tcp" || s.Network == "tcp4" || s.Network == "tcp6" || s.Network == "unix"
+}
+
+// appendMessage appends the RFC 5424 message containing event to dest, with
+// octet counting framing if needed
+func (s *SyslogWriter) appendMessage(dest, event []byte) []byte {
+	severity := 6
+	ts := time.Time{}
+	p := &parser{}
+	if pairs, _, err := p.parse(event); err == nil {
+		for _, pair := range pairs {
+			switch pair.keyUnquoted {
+			case zerolog.LevelFieldName:
+				severity = syslogSeverity(pair.valueBytes)
+			case zerolog.TimestampFieldName:
+				ts, _ = (TimeNormalizer{}).parse(pair.valueBytes)
+			}
+		}
+	}
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	facility := s.Facility
+	if facility == 0 {
+		facility = SyslogFacilityUser
+	}
+	header := make([]byte, 0, 128)
+	header = append(header, '<')
+	header = strconv.AppendInt(header, int64(facility*8+severity), 10)
+	header = append(header, ">1 "...)
+	header = ts.AppendFormat(header, syslogTimestampLayout)
+	header = append(header, ' ')
+	header = appendSyslogHeaderField(header, s.hostname(), 255)
+	header = append(header, ' ')
+	header = appendSyslogHeaderField(header, s.appName(), 48)
+	header = append(header, ' ')
+	header = strconv.AppendInt(header, int64(os.Getpid()), 10)
+	header = append(header, ' ')
+	header = appendSyslogHeaderField(header, s.MsgID, 32)
+	header = append(header, " - "...)
+	if s.framed() {
+		dest = strconv.AppendInt(dest, int64(len(header)+len(event)), 10)
+		dest = append(dest, ' ')
+	}
+	dest = append(dest, header...)
+	return append(dest, event...)
+}
+
+func (s *SyslogWriter) hostname() string {
+	if s.Hostname != "" {
+		return s.Hostname
+	}
+	return localHostname()
+}
+
+func (s *SyslogWriter) appName() string {
+	if s.AppName != "" {
+		return s.AppName
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// appendSyslogHeaderField appends value to dest, truncated to maxLength and
+// with characters that aren't printable ASCII replaced by '_'. If value is
+// empty, the nil value "-" is appended.
+func appendSyslogHeaderField(dest []byte, value string, maxLength int) []byte {
+	if value == "" {
+		return append(dest, '-')
+	}
+	if len(value) > maxLength {
+		value = value[:maxLength]
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 33 || c > 126 {
+			c = '_'
+		}
+		dest = append(dest, c)
+	}
+	return dest
+}
@@ -0,0 +1,180 @@
+package zord
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var syslogMessagePattern = regexp.MustCompile(`^<(\d+)>1 (\S+) example zordtest \d+ - - (\{.*\})$`)
+
+func checkSyslogMessage(t *testing.T, message string, pri string, ts string, event string) {
+	t.Helper()
+	m := syslogMessagePattern.FindStringSubmatch(message)
+	if m == nil {
+		t.Errorf("unexpected message: %s", message)
+		return
+	}
+	if m[1] != pri || m[2] != ts || m[3] != event {
+		t.Errorf("unexpected message: %s", message)
+	}
+}
+
+func newTestSyslogWriter(network, addr string) *SyslogWriter {
+	w := NewSyslogWriter(network, addr)
+	w.Hostname = "example"
+	w.AppName = "zordtest"
+	return w
+}
+
+// readOctetCounted reads a message framed by octet counting
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+	message := make([]byte, n)
+	_, err = io.ReadFull(r, message)
+	return string(message), err
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	output := newTestSyslogWriter("udp", listener.LocalAddr().String())
+	output.Facility = SyslogFacilityLocal0
+	defer output.Close()
+	writer := NewWriter()
+	writer.Output = output
+	if _, err := writer.Write([]byte(`{"message":"hello","level":"error","time":"2006-01-02T15:04:05.123Z"}`)); err != nil {
+		t.Fatal(err)
+	}
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet := make([]byte, 2048)
+	n, _, err := listener.ReadFrom(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSyslogMessage(t, string(packet[:n]), "131", "2006-01-02T15:04:05.123Z",
+		`{"time":"2006-01-02T15:04:05.123Z","level":"error","message":"hello"}`)
+}
+
+func TestSyslogWriterUnixgram(t *testing.T) {
+	dir, err := os.MkdirTemp("", "zord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.sock")
+	listener, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	output := newTestSyslogWriter("unixgram", path)
+	defer output.Close()
+	if _, err := output.Write([]byte("{\"level\":\"debug\",\"time\":1136214245}\n")); err != nil {
+		t.Fatal(err)
+	}
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet := make([]byte, 2048)
+	n, _, err := listener.ReadFrom(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSyslogMessage(t, string(packet[:n]), "15", time.Unix(1136214245, 0).Format(syslogTimestampLayout),
+		`{"level":"debug","time":1136214245}`)
+}
+
+func TestSyslogWriterTCPReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	output := newTestSyslogWriter("tcp", listener.Addr().String())
+	defer output.Close()
+	event := []byte(`{"level":"info","time":"2006-01-02T15:04:05Z","message":"hello"}`)
+
+	// the first connection is closed by the server after one message
+	if _, err := output.Write(event); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	message, err := readOctetCounted(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSyslogMessage(t, message, "14", "2006-01-02T15:04:05Z", string(event))
+	conn.Close()
+
+	// writes to the closed connection may appear to succeed for a while, so
+	// keep writing until the writer reconnects
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	deadline := time.After(5 * time.Second)
+	for {
+		output.Write(event)
+		select {
+		case conn = <-accepted:
+		case <-time.After(10 * time.Millisecond):
+			continue
+		case <-deadline:
+			t.Fatal("writer didn't reconnect")
+		}
+		break
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	message, err = readOctetCounted(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSyslogMessage(t, message, "14", "2006-01-02T15:04:05Z", string(event))
+}
+
+func TestSyslogWriterWriteTimeout(t *testing.T) {
+	// the server never accepts connections, let alone reads from them
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	output := newTestSyslogWriter("tcp", listener.Addr().String())
+	output.Timeout = 50 * time.Millisecond
+	defer output.Close()
+	// larger than the socket buffers, so the write blocks
+	event := []byte(`{"level":"info","message":"` + strings.Repeat("x", 32<<20) + `"}`)
+
+	start := time.Now()
+	_, err = output.Write(event)
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("write took %v", elapsed)
+	}
+}
@@ -0,0 +1,99 @@
+package zord
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestWriter writes reordered events to a test's log with t.Log, so that
+// they're only shown for failing tests (or with go test -v) and are attached
+// to the right subtest. It also keeps the events it writes, so that tests can
+// make assertions about them.
+//
+// Create a TestWriter for each test or subtest with NewTestWriter.
+type TestWriter struct {
+	FirstKeys []string // keys to be moved to the beginning of events
+
+	t      testing.TB
+	mu     sync.Mutex
+	events [][]Field
+}
+
+// NewTestWriter creates a new TestWriter that logs to t. FirstKeys is
+// DefaultFirstKeys().
+func NewTestWriter(t testing.TB) *TestWriter {
+	return &TestWriter{
+		FirstKeys: DefaultFirstKeys(),
+		t:         t,
+	}
+}
+
+func (w *TestWriter) Write(event []byte) (n int, err error) {
+	w.t.Helper()
+	buf := bytes.NewBuffer(make([]byte, 0, len(event)+1))
+	z := Writer{
+		Output:    buf,
+		FirstKeys: w.FirstKeys,
+	}
+	z.Write(event)
+	w.t.Log(strings.TrimSuffix(buf.String(), "\n"))
+	p := &parser{}
+	pairs, n, err := p.parse(buf.Bytes())
+	if err == nil && skipWhitespace(buf.Bytes(), n) == buf.Len() {
+		w.mu.Lock()
+		w.events = append(w.events, fieldsFromPairs(pairs))
+		w.mu.Unlock()
+	}
+	return len(event), nil
+}
+
+// Events returns the fields of the events written so far, in the order they
+// were written. Events that couldn't be parsed are left out.
+func (w *TestWriter) Events() [][]Field {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events := make([][]Field, len(w.events))
+	copy(events, w.events)
+	return events
+}
+
+// Reset forgets the events written so far.
+func (w *TestWriter) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.events = nil
+}
+
+// AssertFields reports a test error unless the event at index i of Events()
+// has the given fields, in the same order. The event may have other fields
+// before, between or after them. Values are compared by their JSON encoding.
+func (w *TestWriter) AssertFields(i int, fields ...Field) {
+	w.t.Helper()
+	events := w.Events()
+	if i < 0 || i >= len(events) {
+		w.t.Errorf("zord: no event %d, %d events were written", i, len(events))
+		return
+	}
+	if j, ok := matchFields(events[i], fields); !ok {
+		w.t.Errorf("zord: event %d has no field %s:%s in the expected order: %s",
+			i, fields[j].Key, fields[j].Value, reorderFields(nil, events[i], nil))
+	}
+}
+
+// matchFields reports whether expected is a subsequence of fields. If it
+// isn't, it also returns the index of the first expected field that wasn't
+// matched.
+func matchFields(fields, expected []Field) (int, bool) {
+	j := 0
+	for _, field := range fields {
+		if j == len(expected) {
+			break
+		}
+		if field.Key == expected[j].Key && bytes.Equal(field.Value, expected[j].Value) {
+			j++
+		}
+	}
+	return j, j == len(expected)
+}
@@ -0,0 +1,65 @@
+package zord
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// recordingTB records the calls TestWriter makes to a testing.TB
+type recordingTB struct {
+	testing.TB
+	logs   []string
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Log(args ...interface{}) {
+	r.logs = append(r.logs, args[0].(string))
+}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func TestTestWriter(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	writer := NewTestWriter(tb)
+	logger := zerolog.New(writer)
+	logger.Info().Str("a", "b").Int("n", 1).Msg("hello")
+	writer.Write([]byte("not json"))
+	expectedLogs := []string{
+		`{"level":"info","message":"hello","a":"b","n":1}`,
+		`not json`,
+	}
+	if strings.Join(tb.logs, "\n") != strings.Join(expectedLogs, "\n") {
+		t.Errorf("unexpected logs: %q", tb.logs)
+	}
+	if events := writer.Events(); len(events) != 1 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+
+	writer.AssertFields(0, StringField("level", "info"), Field{Key: "n", Value: []byte("1")})
+	if len(tb.errors) != 0 {
+		t.Errorf("unexpected errors: %q", tb.errors)
+	}
+	writer.AssertFields(0, Field{Key: "n", Value: []byte("1")}, StringField("a", "b"))
+	writer.AssertFields(1)
+	if len(tb.errors) != 2 {
+		t.Errorf("expected 2 errors, got %q", tb.errors)
+	}
+
+	writer.Reset()
+	if events := writer.Events(); len(events) != 0 {
+		t.Errorf("unexpected events: %v", events)
+	}
+}
+
+func TestTestWriterSubtest(t *testing.T) {
+	t.Run("sub", func(t *testing.T) {
+		logger := zerolog.New(NewTestWriter(t))
+		logger.Info().Msg("attached to the subtest")
+	})
+}
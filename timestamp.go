@@ -0,0 +1,228 @@
+package zord
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// Layouts that can be used with TimeNormalizer to write timestamps as
+// numbers relative to the unix epoch.
+const (
+	TimeFormatUnix      = "UNIX"
+	TimeFormatUnixMs    = "UNIXMS"
+	TimeFormatUnixMicro = "UNIXMICRO"
+	TimeFormatUnixNano  = "UNIXNANO"
+)
+
+// TimeNormalizer is a Transformer that rewrites the timestamp field of
+// events in a single layout and time zone.
+//
+// Timestamps written as strings are recognized if they're in RFC 3339
+// format, zerolog.TimeFieldFormat or one of InputLayouts. Timestamps written
+// as numbers are taken to be relative to the unix epoch, in seconds,
+// milliseconds, microseconds or nanoseconds depending on their magnitude.
+// Values that aren't recognized are left as they are, as are timestamps
+// outside the years 1678 to 2262 when Layout is TimeFormatUnixNano.
+type TimeNormalizer struct {
+	Key          string         // key of the timestamp field. If "", zerolog.TimestampFieldName is used
+	Layout       string         // layout to write timestamps in, or one of the TimeFormatUnix constants. If "", time.RFC3339Nano is used
+	Location     *time.Location // time zone to write timestamps in. If nil, time.UTC is used
+	InputLayouts []string       // additional layouts to recognize
+}
+
+// Transform rewrites the timestamp fields in fields.
+func (t TimeNormalizer) Transform(fields []Field) ([]Field, error) {
+	key := t.Key
+	if key == "" {
+		key = zerolog.TimestampFieldName
+	}
+	for i, field := range fields {
+		if field.Key != key {
+			continue
+		}
+		ts, ok := t.parse(field.Value)
+		if !ok {
+			continue
+		}
+		if value, ok := t.format(ts); ok {
+			fields[i].Value = value
+		}
+	}
+	return fields, nil
+}
+
+func (t TimeNormalizer) parse(value []byte) (time.Time, bool) {
+	if len(value) == 0 {
+		return time.Time{}, false
+	}
+	if value[0] != '"' {
+		return parseUnixTime(value)
+	}
+	s, ok := jsonconv.Unquote(value)
+	if !ok {
+		return time.Time{}, false
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return ts, true
+	}
+	if layout := zerolog.TimeFieldFormat; layout != "" {
+		if ts, err := time.Parse(layout, s); err == nil {
+			return ts, true
+		}
+	}
+	for _, layout := range t.InputLayouts {
+		if ts, err := time.Parse(layout, s); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// format returns ts in t.Layout, or false if it can't be represented, which
+// only happens with TimeFormatUnixNano outside the years 1678 to 2262
+func (t TimeNormalizer) format(ts time.Time) ([]byte, bool) {
+	loc := t.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	ts = ts.In(loc)
+	// UnixNano overflows outside 1678 to 2262, so the other units are
+	// computed from the seconds
+	switch t.Layout {
+	case TimeFormatUnix:
+		return strconv.AppendInt(nil, ts.Unix(), 10), true
+	case TimeFormatUnixMs:
+		return strconv.AppendInt(nil, ts.Unix()*1e3+int64(ts.Nanosecond())/1e6, 10), true
+	case TimeFormatUnixMicro:
+		return strconv.AppendInt(nil, ts.Unix()*1e6+int64(ts.Nanosecond())/1e3, 10), true
+	case TimeFormatUnixNano:
+		nsec := ts.UnixNano()
+		if !time.Unix(0, nsec).Equal(ts) {
+			return nil, false
+		}
+		return strconv.AppendInt(nil, nsec, 10), true
+	case "":
+		return appendTime(nil, ts, time.RFC3339Nano), true
+	default:
+		return appendTime(nil, ts, t.Layout), true
+	}
+}
+
+// parseUnixTime parses a JSON number as a unix timestamp. The unit is
+// guessed from the magnitude of the number: timestamps in seconds won't
+// reach 1e11 until the year 5138.
+func parseUnixTime(value []byte) (time.Time, bool) {
+	if !jsonconv.IsValidNumberBytes(value) {
+		return time.Time{}, false
+	}
+	s := string(value)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		abs := i
+		if abs < 0 {
+			abs = -abs
+		}
+		switch {
+		case abs < 1e11:
+			return time.Unix(i, 0), true
+		case abs < 1e14:
+			return time.Unix(i/1e3, (i%1e3)*1e6), true
+		case abs < 1e17:
+			return time.Unix(i/1e6, (i%1e6)*1e3), true
+		default:
+			return time.Unix(0, i), true
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) {
+		return time.Time{}, false
+	}
+	// the magnitude only picks the unit. the value is converted from its
+	// decimal digits, since float64 can't hold nanosecond precision
+	var unitDigits int
+	switch abs := math.Abs(f); {
+	case abs < 1e11:
+		unitDigits = 9
+	case abs < 1e14:
+		unitDigits = 6
+	case abs < 1e17:
+		unitDigits = 3
+	}
+	sec, nsec, ok := decimalToUnix(s, unitDigits)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, nsec), true
+}
+
+// decimalToUnix converts the valid JSON number s, multiplied by
+// 10^unitDigits to get nanoseconds, into seconds and nanoseconds. The
+// nanoseconds are rounded half away from zero.
+func decimalToUnix(s string, unitDigits int) (sec int64, nsec int64, ok bool) {
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		if exp, err = strconv.Atoi(s[i+1:]); err != nil {
+			return 0, 0, false
+		}
+		s = s[:i]
+	}
+	digits := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		digits = s[:i] + s[i+1:]
+		exp -= len(s) - i - 1
+	}
+	digits = strings.TrimLeft(digits, "0")
+	// the value in nanoseconds is digits * 10^exp
+	exp += unitDigits
+	roundUp := false
+	switch {
+	case digits == "":
+		return 0, 0, true
+	case exp > 0:
+		if len(digits)+exp > 30 {
+			return 0, 0, false
+		}
+		digits += strings.Repeat("0", exp)
+	case exp < 0:
+		if -exp > len(digits) {
+			// less than half a nanosecond
+			digits = ""
+		} else {
+			cut := len(digits) + exp
+			roundUp = digits[cut] >= '5'
+			digits = digits[:cut]
+		}
+	}
+	if len(digits) > 9 {
+		if sec, err := strconv.ParseInt(digits[:len(digits)-9], 10, 64); err == nil {
+			nsec, _ = strconv.ParseInt(digits[len(digits)-9:], 10, 64)
+			return finishUnix(sec, nsec, roundUp, negative)
+		}
+		return 0, 0, false
+	}
+	if digits != "" {
+		nsec, _ = strconv.ParseInt(digits, 10, 64)
+	}
+	return finishUnix(0, nsec, roundUp, negative)
+}
+
+func finishUnix(sec, nsec int64, roundUp, negative bool) (int64, int64, bool) {
+	if roundUp {
+		nsec++
+		if nsec == 1e9 {
+			sec++
+			nsec = 0
+		}
+	}
+	if negative {
+		sec, nsec = -sec, -nsec
+	}
+	return sec, nsec, true
+}
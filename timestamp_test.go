@@ -0,0 +1,139 @@
+package zord
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type timeNormalizerTest struct {
+	desc       string
+	obj        []byte
+	normalizer TimeNormalizer
+	expected   []byte
+}
+
+var timeNormalizerTests = []timeNormalizerTest{
+	{
+		desc:     "rfc3339",
+		obj:      []byte(`{"time":"2006-01-02T08:04:05-07:00","aaa":"foo"}`),
+		expected: []byte(`{"time":"2006-01-02T15:04:05Z","aaa":"foo"}`),
+	},
+	{
+		desc:     "rfc3339 nano",
+		obj:      []byte(`{"time":"2006-01-02T15:04:05.123456789Z"}`),
+		expected: []byte(`{"time":"2006-01-02T15:04:05.123456789Z"}`),
+	},
+	{
+		desc:     "unix seconds",
+		obj:      []byte(`{"time":1136214245}`),
+		expected: []byte(`{"time":"2006-01-02T15:04:05Z"}`),
+	},
+	{
+		desc:     "unix seconds with fraction",
+		obj:      []byte(`{"time":1136214245.5}`),
+		expected: []byte(`{"time":"2006-01-02T15:04:05.5Z"}`),
+	},
+	{
+		desc:     "unix milliseconds",
+		obj:      []byte(`{"time":1136214245123}`),
+		expected: []byte(`{"time":"2006-01-02T15:04:05.123Z"}`),
+	},
+	{
+		desc:     "unix seconds with millisecond fraction",
+		obj:      []byte(`{"time":1136214245.123}`),
+		expected: []byte(`{"time":"2006-01-02T15:04:05.123Z"}`),
+	},
+	{
+		desc:     "unix seconds with exponent",
+		obj:      []byte(`{"time":1.1362142451234567891e9}`),
+		expected: []byte(`{"time":"2006-01-02T15:04:05.123456789Z"}`),
+	},
+	{
+		desc:     "unix milliseconds after 2262",
+		obj:      []byte(`{"time":9999999999999}`),
+		expected: []byte(`{"time":"2286-11-20T17:46:39.999Z"}`),
+	},
+	{
+		desc:     "negative unix milliseconds",
+		obj:      []byte(`{"time":-1136214245123}`),
+		expected: []byte(`{"time":"1933-12-30T08:55:54.877Z"}`),
+	},
+	{
+		desc:     "unix microseconds",
+		obj:      []byte(`{"time":1136214245123456}`),
+		expected: []byte(`{"time":"2006-01-02T15:04:05.123456Z"}`),
+	},
+	{
+		desc:     "unix nanoseconds",
+		obj:      []byte(`{"time":1136214245123456789}`),
+		expected: []byte(`{"time":"2006-01-02T15:04:05.123456789Z"}`),
+	},
+	{
+		desc:       "to unix milliseconds",
+		obj:        []byte(`{"time":"2006-01-02T15:04:05.123456789Z"}`),
+		normalizer: TimeNormalizer{Layout: TimeFormatUnixMs},
+		expected:   []byte(`{"time":1136214245123}`),
+	},
+	{
+		desc:       "to unix milliseconds after 2262",
+		obj:        []byte(`{"time":99999999999}`),
+		normalizer: TimeNormalizer{Layout: TimeFormatUnixMs},
+		expected:   []byte(`{"time":99999999999000}`),
+	},
+	{
+		desc:       "to unix microseconds after 2262",
+		obj:        []byte(`{"time":1e10}`),
+		normalizer: TimeNormalizer{Layout: TimeFormatUnixMicro},
+		expected:   []byte(`{"time":10000000000000000}`),
+	},
+	{
+		desc:       "to unix milliseconds before 1970",
+		obj:        []byte(`{"time":"1933-12-30T08:55:54.877Z"}`),
+		normalizer: TimeNormalizer{Layout: TimeFormatUnixMs},
+		expected:   []byte(`{"time":-1136214245123}`),
+	},
+	{
+		desc:       "to unix nanoseconds after 2262",
+		obj:        []byte(`{"time":1e10}`),
+		normalizer: TimeNormalizer{Layout: TimeFormatUnixNano},
+		expected:   []byte(`{"time":1e10}`),
+	},
+	{
+		desc:       "layout and location",
+		obj:        []byte(`{"ts":1136214245,"time":1136214245}`),
+		normalizer: TimeNormalizer{Key: "ts", Layout: time.RFC1123Z, Location: time.FixedZone("", -7*60*60)},
+		expected:   []byte(`{"time":1136214245,"ts":"Mon, 02 Jan 2006 08:04:05 -0700"}`),
+	},
+	{
+		desc:       "input layouts",
+		obj:        []byte(`{"time":"Jan  2 15:04:05.000"}`),
+		normalizer: TimeNormalizer{InputLayouts: []string{time.StampMilli}},
+		expected:   []byte(`{"time":"0000-01-02T15:04:05Z"}`),
+	},
+	{
+		desc:     "unrecognized",
+		obj:      []byte(`{"time":"yesterday","aaa":"foo"}`),
+		expected: []byte(`{"time":"yesterday","aaa":"foo"}`),
+	},
+}
+
+func TestTimeNormalizer(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	for _, test := range timeNormalizerTests {
+		buf.Reset()
+		normalizer := test.normalizer
+		writer.NormalizeTime = &normalizer
+		_, err := writer.Write(test.obj)
+		if err != nil {
+			t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			continue
+		}
+		result := bytes.TrimRight(buf.Bytes(), "\n")
+		if !bytes.Equal(test.expected, result) {
+			t.Errorf("test \"%s\" unexpected: %s", test.desc, string(result))
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package zord
+
+// TrailingFieldName is the key of the field that Writer adds to events with
+// trailing data when Trailing is TrailingField.
+var TrailingFieldName = "_trailing"
+
+// TrailingPolicy determines what Writer does with data that follows the
+// event object, for example when a library writes twice to the same buffer
+// or appends text to an event.
+type TrailingPolicy int
+
+const (
+	// TrailingRaw writes the whole event as-is, without reordering it.
+	TrailingRaw TrailingPolicy = iota
+	// TrailingObjects writes the event object, then processes the trailing
+	// data as further events. Trailing data that isn't an object is written
+	// as-is, ending with a newline.
+	TrailingObjects
+	// TrailingField adds the trailing data to the event object as a
+	// string field named by TrailingFieldName.
+	TrailingField
+	// TrailingLine writes the event object, then writes the trailing data
+	// as-is on the next line.
+	TrailingLine
+)
@@ -0,0 +1,69 @@
+package zord
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriterTrailing(t *testing.T) {
+	tests := []struct {
+		desc     string
+		policy   TrailingPolicy
+		obj      string
+		expected string
+	}{
+		{"raw", TrailingRaw, "{\"a\":1,\"level\":\"info\"}{\"b\":2}\n", "{\"a\":1,\"level\":\"info\"}{\"b\":2}\n"},
+		{"objects", TrailingObjects, "{\"a\":1,\"level\":\"info\"}{\"b\":2,\"level\":\"warn\"}\n", "{\"level\":\"info\",\"a\":1}\n{\"level\":\"warn\",\"b\":2}\n"},
+		{"objects with text", TrailingObjects, "{\"a\":1,\"level\":\"info\"} oops\n", "{\"level\":\"info\",\"a\":1}\noops\n"},
+		{"objects then text", TrailingObjects, "{\"a\":1}{\"b\":2} oops\n", "{\"a\":1}\n{\"b\":2}\noops\n"},
+		{"objects then text without newline", TrailingObjects, "{\"a\":1} {\"b\":2} garbage", "{\"a\":1}\n{\"b\":2}\ngarbage\n"},
+		{"field", TrailingField, "{\"a\":1,\"level\":\"info\"} oops \"x\"\n", "{\"level\":\"info\",\"a\":1,\"_trailing\":\"oops \\\"x\\\"\"}\n"},
+		{"line", TrailingLine, "{\"a\":1,\"level\":\"info\"}{\"b\":2}\n", "{\"level\":\"info\",\"a\":1}\n{\"b\":2}\n"},
+		{"line without newline", TrailingLine, "{\"a\":1,\"level\":\"info\"} oops", "{\"level\":\"info\",\"a\":1}\noops\n"},
+		{"whitespace only", TrailingLine, "{\"a\":1,\"level\":\"info\"} \n", "{\"level\":\"info\",\"a\":1}\n"},
+		{"invalid object", TrailingLine, "{\"a\":1,\"level\":} x\n", "{\"a\":1,\"level\":} x\n"},
+	}
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	for _, test := range tests {
+		buf.Reset()
+		writer.Trailing = test.policy
+		n, err := writer.Write([]byte(test.obj))
+		if err != nil {
+			t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			continue
+		}
+		if n != len(test.obj) {
+			t.Errorf("test \"%s\" unexpected n: %d", test.desc, n)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("test \"%s\" unexpected: %q", test.desc, buf.String())
+		}
+	}
+}
+
+func TestWriterTrailingNoFirstKeys(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.FirstKeys = nil
+	writer.Trailing = TrailingObjects
+	writer.Write([]byte("{\"a\":1}{\"b\":2}\n"))
+	if buf.String() != "{\"a\":1}\n{\"b\":2}\n" {
+		t.Errorf("unexpected: %q", buf.String())
+	}
+}
+
+func TestWriterTrailingHashChain(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.Trailing = TrailingObjects
+	writer.HashChain = &HashChain{}
+	writer.Write([]byte("{\"a\":1}{\"b\":2}\n"))
+	writer.Write([]byte("{\"c\":3}\n"))
+	if err := VerifyHashChain(buf, "", ""); err != nil {
+		t.Error(err)
+	}
+}
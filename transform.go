@@ -0,0 +1,104 @@
+package zord
+
+import "github.com/7fffffff/jsonconv"
+
+// Field is a key-value pair found at the top level of an event object.
+type Field struct {
+	Key   string // unquoted key
+	Value []byte // JSON encoded value (quoted/with brackets/etc)
+
+	// the key as it appeared in the event, so that it can be written back
+	// unchanged if a Transformer doesn't rename the field
+	keyUnquoted string
+	keyBytes    []byte
+}
+
+// appendKey appends the double-quoted form of f.Key to dest.
+func (f Field) appendKey(dest []byte) []byte {
+	if f.keyBytes != nil && f.Key == f.keyUnquoted {
+		return append(dest, f.keyBytes...)
+	}
+	return jsonconv.AppendQuote(dest, f.Key)
+}
+
+func fieldsFromPairs(pairs []kv) []Field {
+	fields := make([]Field, len(pairs), len(pairs)+4)
+	for i, pair := range pairs {
+		fields[i] = Field{
+			Key:         pair.keyUnquoted,
+			Value:       pair.valueBytes,
+			keyUnquoted: pair.keyUnquoted,
+			keyBytes:    pair.keyBytes,
+		}
+	}
+	return fields
+}
+
+// ParseFields parses event, a JSON object such as a zerolog event, and
+// returns its top level fields in the order they appear, using the same
+// parser as Writer. The field values refer to event. ParseFields returns an
+// error implementing PositionError if event isn't a single JSON object,
+// optionally surrounded by whitespace.
+func ParseFields(event []byte) ([]Field, error) {
+	p := &parser{}
+	pairs, n, err := p.parse(event)
+	if err != nil {
+		return nil, withContext(err, event)
+	}
+	if n = skipWhitespace(event, n); n < len(event) {
+		return nil, withContext(parseErrorAt(n, errTrailingData), event)
+	}
+	return fieldsFromPairs(pairs), nil
+}
+
+// ParseEvents parses data containing a sequence of JSON objects separated by
+// optional whitespace, such as newline delimited events or pretty-printed
+// objects, and returns the top level fields of each object. Errors implement
+// PositionError, with positions within data.
+func ParseEvents(data []byte) ([][]Field, error) {
+	var events [][]Field
+	for pos := skipWhitespace(data, 0); pos < len(data); pos = skipWhitespace(data, pos) {
+		p := &parser{}
+		pairs, n, err := p.parse(data[pos:])
+		if err != nil {
+			return events, withContext(shiftPos(err, pos), data)
+		}
+		events = append(events, fieldsFromPairs(pairs))
+		pos += n
+	}
+	return events, nil
+}
+
+// Transformer edits the top level fields of an event object before Writer
+// reorders them. Transform receives the fields in the order they appear in
+// the event and returns the fields to be written. It may add, remove,
+// modify or reorder fields, and may modify the given slice in place.
+//
+// Values must remain valid JSON. If Transform returns an error or panics,
+// Writer writes the event as-is.
+type Transformer interface {
+	Transform(fields []Field) ([]Field, error)
+}
+
+// TransformerFunc is an adapter to allow the use of ordinary functions as
+// Transformers.
+type TransformerFunc func(fields []Field) ([]Field, error)
+
+// Transform calls f(fields).
+func (f TransformerFunc) Transform(fields []Field) ([]Field, error) {
+	return f(fields)
+}
+
+// fieldAdder is implemented by Transformers that convert events into another
+// format, like GELFTransformer and OTelTransformer. Writer adds the fields
+// that come after the Transformers (Fields, ComputedFields, the truncation
+// marker and the hash) with the last fieldAdder among them, so that those
+// fields follow the format too. addField must not modify fields in place.
+type fieldAdder interface {
+	addField(fields []Field, field Field) ([]Field, error)
+}
+
+// appendField adds field to the end of fields
+func appendField(fields []Field, field Field) ([]Field, error) {
+	return append(fields, field), nil
+}
@@ -0,0 +1,126 @@
+package zord
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type transformTest struct {
+	desc         string
+	obj          []byte
+	firstKeys    []string
+	transformers []Transformer
+	expected     []byte
+}
+
+var errTransform = errors.New("transform failed")
+
+var transformTests = []transformTest{
+	{
+		desc:      "no transformers",
+		obj:       []byte(`{"aaa":"foo", "bbb":"bar"}`),
+		firstKeys: []string{`bbb`},
+		expected:  []byte(`{"bbb":"bar","aaa":"foo"}`),
+	},
+	{
+		desc:      "rename",
+		obj:       []byte(`{"aaa":"foo", "bbb":"bar", "ccc":"qux"}`),
+		firstKeys: []string{`ddd`},
+		transformers: []Transformer{
+			TransformerFunc(func(fields []Field) ([]Field, error) {
+				for i := range fields {
+					if fields[i].Key == "ccc" {
+						fields[i].Key = "ddd"
+					}
+				}
+				return fields, nil
+			}),
+		},
+		expected: []byte(`{"ddd":"qux","aaa":"foo","bbb":"bar"}`),
+	},
+	{
+		desc:      "chain",
+		obj:       []byte(`{"aaa":"foo", "bbb":"bar"}`),
+		firstKeys: []string{`ccc`},
+		transformers: []Transformer{
+			TransformerFunc(func(fields []Field) ([]Field, error) {
+				return fields[1:], nil
+			}),
+			TransformerFunc(func(fields []Field) ([]Field, error) {
+				return append(fields, Field{Key: "ccc", Value: []byte(`[1,2]`)}), nil
+			}),
+		},
+		expected: []byte(`{"ccc":[1,2],"bbb":"bar"}`),
+	},
+	{
+		desc:      "no first keys",
+		obj:       []byte(`{"aaa":"foo", "bbb":"bar"}`),
+		firstKeys: nil,
+		transformers: []Transformer{
+			TransformerFunc(func(fields []Field) ([]Field, error) {
+				fields[0].Value = []byte(`"FOO"`)
+				return fields, nil
+			}),
+		},
+		expected: []byte(`{"aaa":"FOO","bbb":"bar"}`),
+	},
+	{
+		desc:      "error",
+		obj:       []byte(`{"aaa":"foo", "bbb":"bar"}`),
+		firstKeys: []string{`bbb`},
+		transformers: []Transformer{
+			TransformerFunc(func(fields []Field) ([]Field, error) {
+				return nil, errTransform
+			}),
+		},
+		expected: []byte(`{"aaa":"foo", "bbb":"bar"}`),
+	},
+	{
+		desc:      "panic",
+		obj:       []byte(`{"aaa":"foo", "bbb":"bar"}`),
+		firstKeys: []string{`bbb`},
+		transformers: []Transformer{
+			TransformerFunc(func(fields []Field) ([]Field, error) {
+				return fields[5:], nil
+			}),
+		},
+		expected: []byte(`{"aaa":"foo", "bbb":"bar"}`),
+	},
+}
+
+func TestTransformers(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	for _, test := range transformTests {
+		buf.Reset()
+		writer.FirstKeys = test.firstKeys
+		writer.Transformers = test.transformers
+		_, err := writer.Write(test.obj)
+		if err != nil {
+			t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			continue
+		}
+		result := bytes.TrimRight(buf.Bytes(), " \r\n")
+		if !bytes.Equal(test.expected, result) {
+			t.Errorf("test \"%s\" unexpected: %s", test.desc, string(result))
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields([]byte(` {"b":1,"a":"x","b":{"c":[]}} `))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := reorderFields(nil, fields, nil)
+	if string(result) != `{"b":1,"a":"x","b":{"c":[]}}` {
+		t.Errorf("unexpected: %s", result)
+	}
+	for _, event := range []string{`{"a":1} {}`, `{"a":`, `[]`} {
+		if _, err := ParseFields([]byte(event)); err == nil {
+			t.Errorf("expected error for %s", event)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package zord
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/rs/zerolog"
+)
+
+// TruncatedFieldName is the key of the field Writer adds to events with
+// truncated values. Its value is an array of the keys that were truncated.
+// If an event already has the field, it's never truncated itself, and the
+// keys are appended to it if it's an array, or replace it otherwise.
+var TruncatedFieldName = "_truncated"
+
+// truncator shortens values according to a Writer's MaxValueLength and
+// MaxEventSize.
+type truncator struct {
+	maxValueLength int
+	maxEventSize   int
+	add            func([]Field, Field) ([]Field, error) // adds the marker. If nil, appendField is used
+}
+
+func (t truncator) addField(fields []Field, field Field) ([]Field, error) {
+	if t.add == nil {
+		return appendField(fields, field)
+	}
+	return t.add(fields, field)
+}
+
+func (t truncator) Transform(fields []Field) ([]Field, error) {
+	truncated := make([]bool, len(fields))
+	numTruncated := 0
+	if t.maxValueLength > 0 {
+		for i, field := range fields {
+			if !isStringLiteral(field.Value) || field.Key == TruncatedFieldName {
+				continue
+			}
+			value, ok := truncateString(field.Value, t.maxValueLength, true)
+			if ok {
+				fields[i].Value = value
+				truncated[i] = true
+				numTruncated++
+			}
+		}
+	}
+	if t.maxEventSize > 0 && t.canFit(fields, truncated) {
+		size := objectSize(fields)
+		for {
+			excess := size - t.maxEventSize
+			if numTruncated > 0 {
+				excess += t.markerSize(fields, truncated)
+			}
+			if excess <= 0 {
+				break
+			}
+			if !t.shrinkLargest(fields, truncated, excess) {
+				break
+			}
+			numTruncated = 0
+			for _, ok := range truncated {
+				if ok {
+					numTruncated++
+				}
+			}
+			size = objectSize(fields)
+		}
+	}
+	if numTruncated == 0 {
+		return fields, nil
+	}
+	if existing := truncatedFieldIndex(fields); existing >= 0 {
+		fields[existing].Value = truncationMarker(fields, truncated)
+		return fields, nil
+	}
+	return t.addField(fields, Field{Key: TruncatedFieldName, Value: truncationMarker(fields, truncated)})
+}
+
+// truncatedFieldIndex returns the index of the TruncatedFieldName field in
+// fields, or -1 if there isn't one
+func truncatedFieldIndex(fields []Field) int {
+	for i, field := range fields {
+		if field.Key == TruncatedFieldName {
+			return i
+		}
+	}
+	return -1
+}
+
+// truncationMarker returns the value of the TruncatedFieldName field: the
+// keys of the truncated fields, after those already in the existing field if
+// it's an array
+func truncationMarker(fields []Field, truncated []bool) []byte {
+	marker := make([]byte, 0, 64)
+	marker = append(marker, '[')
+	if existing := truncatedFieldIndex(fields); existing >= 0 {
+		if value := fields[existing].Value; len(value) >= 2 && value[0] == '[' && value[len(value)-1] == ']' {
+			marker = append(marker, bytes.TrimSpace(value[1:len(value)-1])...)
+		}
+	}
+	for i, field := range fields {
+		if !truncated[i] {
+			continue
+		}
+		if len(marker) > 1 {
+			marker = append(marker, ',')
+		}
+		marker = jsonconv.AppendQuote(marker, field.Key)
+	}
+	return append(marker, ']')
+}
+
+// shrinkable reports whether MaxEventSize may shorten the value of the field
+// named key. The level, timestamp and message are kept, as is the truncation
+// marker.
+func shrinkable(key string) bool {
+	switch key {
+	case TruncatedFieldName, zerolog.LevelFieldName, zerolog.TimestampFieldName, zerolog.MessageFieldName:
+		return false
+	}
+	return true
+}
+
+// canFit reports whether fields can be made to fit in t.maxEventSize by
+// shrinking values. If they can't, they're left as they are rather than
+// losing every shrinkable value for nothing.
+func (t truncator) canFit(fields []Field, truncated []bool) bool {
+	shrunk := make([]Field, len(fields))
+	copy(shrunk, fields)
+	shrunkTruncated := make([]bool, len(truncated))
+	copy(shrunkTruncated, truncated)
+	anyTruncated := false
+	for i, field := range shrunk {
+		if shrinkable(field.Key) && len(field.Value) > len(`""`) {
+			shrunk[i].Value = []byte(`""`)
+			shrunkTruncated[i] = true
+		}
+		anyTruncated = anyTruncated || shrunkTruncated[i]
+	}
+	size := objectSize(shrunk)
+	if anyTruncated {
+		size += t.markerSize(shrunk, shrunkTruncated)
+	}
+	return size <= t.maxEventSize
+}
+
+// shrinkLargest removes excess bytes from the largest values in fields, but
+// doesn't shorten them below the length of the next largest value. Values
+// that aren't strings are converted to strings containing their JSON
+// encoding before being shortened. The growth of the truncation marker is
+// included in excess. shrinkLargest marks the shortened fields
+// in truncated and reports whether any value was shortened.
+func (t truncator) shrinkLargest(fields []Field, truncated []bool, excess int) bool {
+	const minLength = len(`""`)
+	largest, next, count := minLength, minLength, 0
+	for _, field := range fields {
+		if !shrinkable(field.Key) {
+			continue
+		}
+		switch n := len(field.Value); {
+		case n <= minLength:
+		case n > largest:
+			largest, next, count = n, largest, 1
+		case n == largest:
+			count++
+		case n > next:
+			next = n
+		}
+	}
+	if count == 0 {
+		return false
+	}
+	// values that haven't been truncated yet will make the truncation
+	// marker larger
+	numTruncated := 0
+	for _, ok := range truncated {
+		if ok {
+			numTruncated++
+		}
+	}
+	for i, field := range fields {
+		if len(field.Value) != largest || truncated[i] || !shrinkable(field.Key) {
+			continue
+		}
+		if numTruncated == 0 {
+			excess += t.markerSize(fields, truncated)
+		} else {
+			excess++
+		}
+		excess += len(jsonconv.Quote(field.Key))
+		numTruncated++
+	}
+	target := largest - (excess+count-1)/count
+	if target < next {
+		target = next
+	}
+	changed := false
+	for i, field := range fields {
+		value := field.Value
+		if len(value) != largest || !shrinkable(field.Key) {
+			continue
+		}
+		if !isStringLiteral(value) {
+			value = jsonconv.QuoteBytes(value)
+		}
+		value, _ = truncateString(value, target-minLength, false)
+		if len(value) < len(field.Value) {
+			fields[i].Value = value
+			truncated[i] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// objectSize returns the length of the object made of fields
+func objectSize(fields []Field) int {
+	size := len(`{}`)
+	for i, field := range fields {
+		if i > 0 {
+			size++
+		}
+		if field.keyBytes != nil && field.Key == field.keyUnquoted {
+			size += len(field.keyBytes)
+		} else {
+			size += len(jsonconv.Quote(field.Key))
+		}
+		size += 1 + len(field.Value)
+	}
+	return size
+}
+
+// markerSize returns the number of bytes that the truncation marker adds to
+// an object
+func (t truncator) markerSize(fields []Field, truncated []bool) int {
+	marker := truncationMarker(fields, truncated)
+	if existing := truncatedFieldIndex(fields); existing >= 0 {
+		return len(marker) - len(fields[existing].Value)
+	}
+	withMarker, err := t.addField(fields[:len(fields):len(fields)], Field{Key: TruncatedFieldName, Value: marker})
+	if err != nil {
+		return 0
+	}
+	return objectSize(withMarker) - objectSize(fields)
+}
+
+func isStringLiteral(value []byte) bool {
+	return len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"'
+}
+
+// truncateString shortens the JSON string literal s so that its content is
+// at most limit bytes long. If unescaped is true, the content is measured
+// after unescaping, otherwise the length of the literal content is used.
+// Escape sequences, surrogate pairs and UTF-8 encoded runes are never cut.
+// truncateString reports whether s was shortened.
+func truncateString(s []byte, limit int, unescaped bool) ([]byte, bool) {
+	content := s[1 : len(s)-1]
+	length := 0
+	for i := 0; i < len(content); {
+		size, decodedSize := stringUnitSize(content, i)
+		if !unescaped {
+			decodedSize = size
+		}
+		if length+decodedSize > limit {
+			truncated := make([]byte, 0, i+2)
+			truncated = append(truncated, '"')
+			truncated = append(truncated, content[:i]...)
+			return append(truncated, '"'), true
+		}
+		length += decodedSize
+		i += size
+	}
+	return s, false
+}
+
+// stringUnitSize returns the length of the escape sequence, surrogate pair
+// or UTF-8 encoded rune at content[i:], and the number of bytes it
+// represents once unescaped.
+func stringUnitSize(content []byte, i int) (size int, decodedSize int) {
+	b := content[i]
+	if b == '\\' && i+1 < len(content) {
+		if content[i+1] != 'u' || i+6 > len(content) {
+			return 2, 1
+		}
+		r := getu4(content[i+2 : i+6])
+		if utf16IsHighSurrogate(r) && i+12 <= len(content) && content[i+6] == '\\' && content[i+7] == 'u' {
+			if utf16IsLowSurrogate(getu4(content[i+8 : i+12])) {
+				return 12, 4
+			}
+		}
+		if r < 0 {
+			return 6, 1
+		}
+		return 6, utf8.RuneLen(replaceSurrogate(r))
+	}
+	if b < utf8.RuneSelf {
+		return 1, 1
+	}
+	_, size = utf8.DecodeRune(content[i:])
+	return size, size
+}
+
+// getu4 decodes 4 hex digits into a rune, or returns -1
+func getu4(s []byte) rune {
+	var r rune
+	for _, c := range s {
+		switch {
+		case '0' <= c && c <= '9':
+			c = c - '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return -1
+		}
+		r = r*16 + rune(c)
+	}
+	return r
+}
+
+func utf16IsHighSurrogate(r rune) bool {
+	return 0xD800 <= r && r < 0xDC00
+}
+
+func utf16IsLowSurrogate(r rune) bool {
+	return 0xDC00 <= r && r < 0xE000
+}
+
+// replaceSurrogate returns utf8.RuneError if r is a lone surrogate
+func replaceSurrogate(r rune) rune {
+	if 0xD800 <= r && r < 0xE000 {
+		return utf8.RuneError
+	}
+	return r
+}
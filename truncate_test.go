@@ -0,0 +1,135 @@
+package zord
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+type truncateTest struct {
+	desc           string
+	obj            []byte
+	maxValueLength int
+	maxEventSize   int
+	expected       []byte
+}
+
+var truncateTests = []truncateTest{
+	{
+		desc:           "short values",
+		obj:            []byte(`{"aaa":"foo","bbb":123456789}`),
+		maxValueLength: 3,
+		expected:       []byte(`{"aaa":"foo","bbb":123456789}`),
+	},
+	{
+		desc:           "long value",
+		obj:            []byte(`{"aaa":"foobar","bbb":"bar"}`),
+		maxValueLength: 3,
+		expected:       []byte(`{"aaa":"foo","bbb":"bar","_truncated":["aaa"]}`),
+	},
+	{
+		desc:           "escape sequences",
+		obj:            []byte(`{"aaa":"\n\n\n\n","bbb":"éé","ccc":"𝄞x"}`),
+		maxValueLength: 3,
+		expected:       []byte(`{"aaa":"\n\n\n","bbb":"é","ccc":"","_truncated":["aaa","bbb","ccc"]}`),
+	},
+	{
+		desc:           "runes",
+		obj:            []byte(`{"aaa":"¼¼","bbb":"😎"}`),
+		maxValueLength: 3,
+		expected:       []byte(`{"aaa":"¼","bbb":"","_truncated":["aaa","bbb"]}`),
+	},
+	{
+		desc:           "existing marker",
+		obj:            []byte(`{"aaa":"foobar","_truncated":["zzz"]}`),
+		maxValueLength: 3,
+		expected:       []byte(`{"aaa":"foo","_truncated":["zzz","aaa"]}`),
+	},
+	{
+		desc:           "existing marker not an array",
+		obj:            []byte(`{"aaa":"foobar","_truncated":"foobar"}`),
+		maxValueLength: 3,
+		expected:       []byte(`{"aaa":"foo","_truncated":["aaa"]}`),
+	},
+	{
+		desc:           "existing marker only",
+		obj:            []byte(`{"aaa":"foo","_truncated":"foobar"}`),
+		maxValueLength: 3,
+		expected:       []byte(`{"aaa":"foo","_truncated":"foobar"}`),
+	},
+	{
+		desc:         "fits",
+		obj:          []byte(`{"aaa":"foobar","bbb":"bar"}`),
+		maxEventSize: 28,
+		expected:     []byte(`{"aaa":"foobar","bbb":"bar"}`),
+	},
+	{
+		desc:         "largest first",
+		obj:          []byte(`{"aaa":"foobarbazquxquuxcorgegrault","bbb":"bar"}`),
+		maxEventSize: 46,
+		expected:     []byte(`{"aaa":"foo","bbb":"bar","_truncated":["aaa"]}`),
+	},
+	{
+		desc:         "several values",
+		obj:          []byte(`{"aaa":"foobarbazquxquuxcorgegraultgarply","bbb":"barbazquxquux","ccc":1}`),
+		maxEventSize: 60,
+		expected:     []byte(`{"aaa":"foo","bbb":"bar","ccc":1,"_truncated":["aaa","bbb"]}`),
+	},
+	{
+		desc:         "existing large marker",
+		obj:          []byte(`{"aaa":"foobarbazquxquux","_truncated":["zzzzzzzzzzzzzzzzzzzz"]}`),
+		maxEventSize: 57,
+		expected:     []byte(`{"aaa":"foo","_truncated":["zzzzzzzzzzzzzzzzzzzz","aaa"]}`),
+	},
+	{
+		desc:         "standard fields kept",
+		obj:          []byte(`{"level":"info","message":"hello","a":"foobarbazquxquuxcorgegrault"}`),
+		maxEventSize: 63,
+		expected:     []byte(`{"level":"info","message":"hello","a":"foo","_truncated":["a"]}`),
+	},
+	{
+		desc:         "object value",
+		obj:          []byte(`{"aaa":{"bbb":"ccc","ddd":"eee","fff":"ggg"},"ddd":1}`),
+		maxEventSize: 45,
+		expected:     []byte(`{"aaa":"{\"bbb","ddd":1,"_truncated":["aaa"]}`),
+	},
+}
+
+func TestTruncate(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	for _, test := range truncateTests {
+		buf.Reset()
+		writer.MaxValueLength = test.maxValueLength
+		writer.MaxEventSize = test.maxEventSize
+		_, err := writer.Write(test.obj)
+		if err != nil {
+			t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			continue
+		}
+		result := bytes.TrimRight(buf.Bytes(), "\n")
+		if !bytes.Equal(test.expected, result) {
+			t.Errorf("test \"%s\" unexpected: %s", test.desc, string(result))
+		}
+		if !json.Valid(result) {
+			t.Errorf("test \"%s\" invalid JSON: %s", test.desc, string(result))
+		}
+		if test.maxEventSize > 0 && len(result) > test.maxEventSize {
+			t.Errorf("test \"%s\" too large: %d", test.desc, len(result))
+		}
+	}
+}
+
+func TestTruncateImpossibleBudget(t *testing.T) {
+	// even without a and b, the event doesn't fit, so nothing is lost
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	writer.MaxEventSize = 40
+	obj := `{"level":"info","message":"a long message","a":"foobar","b":"bar"}`
+	writer.Write([]byte(obj))
+	if buf.String() != obj+"\n" {
+		t.Errorf("unexpected: %s", buf.String())
+	}
+}
@@ -0,0 +1,98 @@
+package zord
+
+import "strings"
+
+// Unflattener is a Transformer that groups fields with keys containing
+// Separator into nested objects. For example, {"http.method":"GET"} becomes
+// {"http":{"method":"GET"}}. The nested object is positioned where the first
+// of its fields was.
+//
+// If there's already a field named after the parent key and its value is an
+// object, the fields of that object are merged with the grouped fields, in
+// the order they appear. If the parent key has a value that isn't an object,
+// the parent and all of the fields that would be grouped under it are left
+// as they are. Duplicate keys are not deduplicated, so grouping two fields
+// with the same key results in an object with duplicate keys.
+//
+// Keys that begin or end with Separator aren't grouped.
+type Unflattener struct {
+	Separator string // separates the keys of parents and children. If "", "." is used
+}
+
+func (u Unflattener) separator() string {
+	if u.Separator == "" {
+		return "."
+	}
+	return u.Separator
+}
+
+// split returns the parent key and the rest of key, or false if key
+// shouldn't be grouped
+func (u Unflattener) split(key string) (parent, rest string, ok bool) {
+	sep := u.separator()
+	i := strings.Index(key, sep)
+	if i <= 0 || i+len(sep) >= len(key) {
+		return "", "", false
+	}
+	return key[:i], key[i+len(sep):], true
+}
+
+// Transform groups fields into nested objects.
+func (u Unflattener) Transform(fields []Field) ([]Field, error) {
+	// find the parent keys, and rule out any that collide with a value that
+	// isn't an object
+	parents := map[string]bool{}
+	for _, field := range fields {
+		if parent, _, ok := u.split(field.Key); ok {
+			parents[parent] = true
+		}
+	}
+	if len(parents) == 0 {
+		return fields, nil
+	}
+	for _, field := range fields {
+		if parents[field.Key] && !isObjectLiteral(field.Value) {
+			parents[field.Key] = false
+		}
+	}
+	unflattened := make([]Field, 0, len(fields))
+	children := map[string][]Field{}
+	positions := map[string]int{}
+	p := &parser{}
+	for _, field := range fields {
+		parent, rest, ok := u.split(field.Key)
+		if !ok || !parents[parent] {
+			if !parents[field.Key] {
+				unflattened = append(unflattened, field)
+				continue
+			}
+			// an object to be merged with the grouped fields
+			parent = field.Key
+		}
+		if _, ok := positions[parent]; !ok {
+			positions[parent] = len(unflattened)
+			unflattened = append(unflattened, Field{Key: parent})
+		}
+		if rest != "" {
+			children[parent] = append(children[parent], Field{Key: rest, Value: field.Value})
+			continue
+		}
+		pairs, _, err := p.parse(field.Value)
+		if err != nil {
+			return fields, err
+		}
+		children[parent] = append(children[parent], fieldsFromPairs(pairs)...)
+	}
+	for parent, i := range positions {
+		grouped, err := u.Transform(children[parent])
+		if err != nil {
+			return fields, err
+		}
+		unflattened[i].Value = reorderFields(nil, grouped, nil)
+	}
+	return unflattened, nil
+}
+
+func isObjectLiteral(value []byte) bool {
+	return len(value) > 0 && value[0] == '{'
+}
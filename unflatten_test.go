@@ -0,0 +1,80 @@
+package zord
+
+import (
+	"bytes"
+	"testing"
+)
+
+type unflattenTest struct {
+	desc        string
+	obj         []byte
+	firstKeys   []string
+	unflattener Unflattener
+	expected    []byte
+}
+
+var unflattenTests = []unflattenTest{
+	{
+		desc:     "no dotted keys",
+		obj:      []byte(`{"aaa":"foo", "bbb":{"ccc":1}, "aaa":"bar"}`),
+		expected: []byte(`{"aaa":"foo","bbb":{"ccc":1},"aaa":"bar"}`),
+	},
+	{
+		desc:      "dotted keys",
+		obj:       []byte(`{"aaa":"foo", "http.method":"GET", "bbb":1, "http.status":200}`),
+		firstKeys: []string{`bbb`},
+		expected:  []byte(`{"bbb":1,"aaa":"foo","http":{"method":"GET","status":200}}`),
+	},
+	{
+		desc:     "deeply dotted keys",
+		obj:      []byte(`{"a.b.c":1, "a.b.d":2, "a.e":3}`),
+		expected: []byte(`{"a":{"b":{"c":1,"d":2},"e":3}}`),
+	},
+	{
+		desc:     "merge with object",
+		obj:      []byte(`{"http.method":"GET", "http":{"status":200, "request":{"id":"x"}}, "http.request.size":10}`),
+		expected: []byte(`{"http":{"method":"GET","status":200,"request":{"id":"x","size":10}}}`),
+	},
+	{
+		desc:     "collision",
+		obj:      []byte(`{"http.method":"GET", "http":"yes", "http.status":200, "aaa.bbb":1}`),
+		expected: []byte(`{"http.method":"GET","http":"yes","http.status":200,"aaa":{"bbb":1}}`),
+	},
+	{
+		desc:     "nested collision",
+		obj:      []byte(`{"a.b":1, "a.b.c":2}`),
+		expected: []byte(`{"a":{"b":1,"b.c":2}}`),
+	},
+	{
+		desc:     "duplicates",
+		obj:      []byte(`{"a.b":1, "a.b":2}`),
+		expected: []byte(`{"a":{"b":1,"b":2}}`),
+	},
+	{
+		desc:        "separator",
+		obj:         []byte(`{".a":1, "b.":2, "c__d":3, "c.e":4}`),
+		unflattener: Unflattener{Separator: "__"},
+		expected:    []byte(`{".a":1,"b.":2,"c":{"d":3},"c.e":4}`),
+	},
+}
+
+func TestUnflatten(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	writer := NewWriter()
+	writer.Output = buf
+	for _, test := range unflattenTests {
+		buf.Reset()
+		unflattener := test.unflattener
+		writer.FirstKeys = test.firstKeys
+		writer.Unflatten = &unflattener
+		_, err := writer.Write(test.obj)
+		if err != nil {
+			t.Errorf("test \"%s\" failed: %v", test.desc, err)
+			continue
+		}
+		result := bytes.TrimRight(buf.Bytes(), "\n")
+		if !bytes.Equal(test.expected, result) {
+			t.Errorf("test \"%s\" unexpected: %s", test.desc, string(result))
+		}
+	}
+}
@@ -9,16 +9,77 @@ import (
 // keys according to FirstKeys, before writing to Output. Writer does not
 // deduplicate keys.
 //
+// Before reordering, the top level fields are passed through each of
+// Transformers in turn, then Fields and ComputedFields are appended, then
+// NormalizeTime, Flatten and Unflatten (if not nil) are applied. The added
+// fields are positioned by FirstKeys like any other key.
+//
+// If MaxValueLength is greater than 0, string values longer than
+// MaxValueLength bytes (once unescaped) are shortened. If MaxEventSize is
+// greater than 0 and the event object would be larger than MaxEventSize
+// bytes, the largest values are shortened first until it fits. Values that
+// aren't strings are replaced by a string of their JSON encoding before being
+// shortened. The level, timestamp and message aren't shortened to fit
+// MaxEventSize, and events that can't fit even without the other values are
+// left as they are. Events with shortened values have a field named by
+// TruncatedFieldName listing the affected keys.
+//
+// If Canonical is true, events are written in the RFC 8785 JSON
+// Canonicalization Scheme form (see Canonicalize) instead of being reordered
+// by FirstKeys. Events with duplicate keys at any depth can't be
+// canonicalized, so they're written as-is.
+//
+// If HashChain is not nil, a hash linking each event to the previous one is
+// added last, after truncation.
+//
+// If Sampler is not nil, events it suppresses are not written at all. If
+// Collapse is not nil, consecutive duplicate events are collapsed.
+//
+// Data that follows the event object is handled according to Trailing. By
+// default, the whole event is written as-is.
+//
+// If RepairUTF8 is true, invalid UTF-8 in keys and string values is
+// replaced by U+FFFD and lone surrogate escapes by \ufffd before parsing,
+// instead of the event being written as-is (or rejected in strict mode).
+//
+// Events nested more than MaxDepth levels deep can't be parsed.
+//
 // If the reordering process fails, Writer will write the log event as-is
-// without signalling the parsing error.
+// without signalling the parsing error, other than by calling OnError. Parse
+// errors passed to OnError implement PositionError. If Strict is true, Writer instead
+// writes nothing and returns an error. Strict mode also rejects events with
+// trailing data (regardless of Trailing), invalid UTF-8, lone surrogate
+// escapes or duplicate keys at any depth. Errors about the event's content
+// wrap an error with a Pos method that returns the position of the problem.
 //
 // If compiled with the binary_log build tag, Writer will not inspect or
 // reorder the data written to it.
 type Writer struct {
-	Output    io.Writer // output writer
-	FirstKeys []string  // keys to be moved to the beginning of event objects
+	Output         io.Writer       // output writer
+	FirstKeys      []string        // keys to be moved to the beginning of event objects
+	Transformers   []Transformer   // applied in order to the fields of event objects
+	Fields         []Field         // static fields added to every event object
+	ComputedFields []ComputedField // computed fields added to every event object
+	NormalizeTime  *TimeNormalizer // rewrites the timestamp field, if not nil
+	Flatten        *Flattener      // expands nested objects into top level fields, if not nil
+	Unflatten      *Unflattener    // groups dotted keys into nested objects, if not nil
+	MaxValueLength int             // maximum length of string values, if > 0
+	MaxEventSize   int             // maximum size of event objects, if > 0
+	Sampler        *Sampler        // rate limits and samples events, if not nil
+	Collapse       *Collapser      // collapses consecutive duplicate events, if not nil
+	Canonical      bool            // writes events in canonical (RFC 8785) form, ignoring FirstKeys
+	HashChain      *HashChain      // adds a hash linking each event to the previous one, if not nil
+	Strict         bool            // returns errors for invalid events instead of writing them as-is
+	RepairUTF8     bool            // replaces invalid UTF-8 and lone surrogates in strings
+	Trailing       TrailingPolicy  // handling of data after the event object
+	OnError        ErrorFunc       // called when an event is written as-is because of an error, if not nil
+	MaxDepth       int             // maximum nesting depth of events. If 0, the limit is 10000, like encoding/json
 }
 
+// ErrorFunc is called with an event that Writer couldn't process, and the
+// reason.
+type ErrorFunc func(event []byte, err error)
+
 // NewWriter creates a new Writer. The default output writer is
 // os.Stderr and the default list of keys is defined by DefaultFirstKeys()
 func NewWriter() *Writer {
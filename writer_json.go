@@ -4,36 +4,134 @@
 package zord
 
 import (
+	"bytes"
 	"fmt"
+
+	"github.com/7fffffff/jsonconv"
 )
 
 func (z Writer) Write(event []byte) (n int, err error) {
-	obj := make([]byte, 0, len(event))
-	obj, n, err = tryReorder(obj, event, z.FirstKeys)
+	if z.Sampler != nil {
+		// the summary timer writes too
+		z.Sampler.writeMu.Lock()
+		defer z.Sampler.writeMu.Unlock()
+		if summary := z.Sampler.summary(); summary != nil {
+			if _, err = z.writeCollapsed(summary); err != nil {
+				return 0, err
+			}
+		}
+		if !z.Sampler.allow(event, z.writeCollapsed) {
+			return len(event), nil
+		}
+	}
+	return z.writeCollapsed(event)
+}
+
+// writeCollapsed writes event through z.Collapse, if set. Everything is
+// written this way, so that the Collapser's lock serializes writes with
+// those of its timer.
+func (z Writer) writeCollapsed(event []byte) (n int, err error) {
+	if z.Collapse != nil {
+		return z.Collapse.write(event, z.write)
+	}
+	return z.write(event)
+}
+
+func (z Writer) write(event []byte) (n int, err error) {
+	if z.RepairUTF8 {
+		if repaired, ok := repairUTF8(event); ok {
+			z.RepairUTF8 = false
+			if _, err = z.write(repaired); err != nil {
+				return 0, err
+			}
+			return len(event), nil
+		}
+	}
+	if z.Strict {
+		if err = validateStrict(event, z.MaxDepth); err != nil {
+			return 0, fmt.Errorf("zord: invalid event: %w", withContext(err, event))
+		}
+	}
+	if z.HashChain != nil {
+		// hold the lock until the event is written, so that events are
+		// written in the order they're chained
+		z.HashChain.mu.Lock()
+		defer z.HashChain.mu.Unlock()
+	}
+	n, err = z.writeObject(event)
 	if err != nil {
+		return 0, err
+	}
+	return len(event), nil
+}
+
+// writeObject reorders and writes the object in event, then handles any
+// data after it according to z.Trailing. z.HashChain.mu must be held.
+func (z Writer) writeObject(event []byte) (n int, err error) {
+	if z.HashChain != nil {
+		z.HashChain.next = nil
+	}
+	obj := make([]byte, 0, len(event)+1)
+	obj, n, err = z.tryReorder(obj, event)
+	if err != nil {
+		if z.Strict {
+			return 0, fmt.Errorf("zord: %w", withContext(err, event))
+		}
 		// If there's an error in the reordering process, it's more
 		// important that the log data get written. So write the event
 		// data as-is.
+		z.reportError(event, err)
 		return z.Output.Write(event)
 	}
 	if n < len(event) {
 		n = skipWhitespace(event, n)
-		if n < len(event) {
+		if n < len(event) && z.Trailing == TrailingRaw {
 			// Parsing succeeded but there's unconsumed, non-whitespace
 			// bytes after the end of the object. Give up and write the
 			// event as-is.
+			z.reportError(event, parseErrorAt(n, errTrailingData))
 			return z.Output.Write(event)
 		}
 	}
+	// write the event and newline together, so that outputs see one
+	// event per Write
+	obj = append(obj, '\n')
 	_, err = z.Output.Write(obj)
 	if err != nil {
-		return n, err
+		return 0, err
+	}
+	if z.HashChain != nil {
+		z.HashChain.commit()
+	}
+	if n < len(event) {
+		// the remainder may be written as-is, so it needs its own newline
+		rest := event[n:]
+		if rest[len(rest)-1] != '\n' {
+			rest = append(rest[:len(rest):len(rest)], '\n')
+		}
+		switch z.Trailing {
+		case TrailingObjects:
+			if _, err = z.writeObject(rest); err != nil {
+				return 0, err
+			}
+		case TrailingLine:
+			if _, err = z.Output.Write(rest); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(event), nil
+}
+
+// reportError passes err, with the line and column of its position within
+// event, to z.OnError
+func (z Writer) reportError(event []byte, err error) {
+	if z.OnError != nil {
+		z.OnError(event, withContext(err, event))
 	}
-	_, err = z.Output.Write([]byte("\n"))
-	return n, err
 }
 
-func tryReorder(dest, src []byte, firstKeys []string) (extended []byte, n int, err error) {
+func (z Writer) tryReorder(dest, src []byte) (extended []byte, n int, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if recoveredErr, ok := r.(error); ok {
@@ -43,5 +141,119 @@ func tryReorder(dest, src []byte, firstKeys []string) (extended []byte, n int, e
 			}
 		}
 	}()
-	return reorder(dest, src, firstKeys)
+	return z.reorder(dest, src)
+}
+
+// transforms reports whether z does more than just reorder keys
+func (z Writer) transforms() bool {
+	return len(z.Transformers) > 0 ||
+		z.NormalizeTime != nil ||
+		z.Flatten != nil ||
+		z.Unflatten != nil ||
+		len(z.Fields) > 0 ||
+		len(z.ComputedFields) > 0 ||
+		z.MaxValueLength > 0 ||
+		z.MaxEventSize > 0 ||
+		z.Canonical ||
+		z.HashChain != nil ||
+		z.Trailing == TrailingField
+}
+
+// reorder is like the reorder function, but also transforms the fields of
+// src as configured by z
+func (z Writer) reorder(dest, src []byte) ([]byte, int, error) {
+	// without first keys, the reorder function copies src whole, trailing
+	// data included, which is only right for TrailingRaw
+	if !z.transforms() && z.MaxDepth == 0 && z.Trailing == TrailingRaw {
+		return reorder(dest, src, z.FirstKeys)
+	}
+	parser := &parser{MaxDepth: z.MaxDepth}
+	pairs, n, err := parser.parse(src)
+	if err != nil {
+		return dest, n, err
+	}
+	fields := fieldsFromPairs(pairs)
+	if z.Trailing == TrailingField {
+		if end := skipWhitespace(src, n); end < len(src) {
+			fields = append(fields, Field{
+				Key:   TrailingFieldName,
+				Value: jsonconv.QuoteBytes(bytes.TrimRight(src[end:], " \t\r\n")),
+			})
+			n = len(src)
+		}
+	}
+	fields, err = z.transform(fields)
+	if err != nil {
+		return dest, n, err
+	}
+	if z.Canonical {
+		dest, err = appendCanonical(dest, fields)
+		return dest, n, err
+	}
+	return reorderFields(dest, fields, z.FirstKeys), n, nil
+}
+
+// transform applies z.Transformers, adds z.Fields and z.ComputedFields,
+// applies z.NormalizeTime, z.Flatten and z.Unflatten, truncates values and
+// then adds the z.HashChain field
+func (z Writer) transform(fields []Field) ([]Field, error) {
+	var err error
+	add := appendField
+	for _, t := range z.Transformers {
+		fields, err = t.Transform(fields)
+		if err != nil {
+			return fields, err
+		}
+		if adder, ok := t.(fieldAdder); ok {
+			add = adder.addField
+		}
+	}
+	for _, field := range z.Fields {
+		fields, err = add(fields, field)
+		if err != nil {
+			return fields, err
+		}
+	}
+	for _, computed := range z.ComputedFields {
+		fields, err = add(fields, Field{Key: computed.Key, Value: computed.Value()})
+		if err != nil {
+			return fields, err
+		}
+	}
+	if z.NormalizeTime != nil {
+		fields, err = z.NormalizeTime.Transform(fields)
+		if err != nil {
+			return fields, err
+		}
+	}
+	if z.Flatten != nil {
+		flattener := *z.Flatten
+		if flattener.MaxDepth == 0 {
+			flattener.MaxDepth = z.MaxDepth
+		}
+		fields, err = flattener.Transform(fields)
+		if err != nil {
+			return fields, err
+		}
+	}
+	if z.Unflatten != nil {
+		fields, err = z.Unflatten.Transform(fields)
+		if err != nil {
+			return fields, err
+		}
+	}
+	if z.MaxValueLength > 0 || z.MaxEventSize > 0 {
+		fields, err = truncator{
+			maxValueLength: z.MaxValueLength,
+			maxEventSize:   z.MaxEventSize,
+			add:            add,
+		}.Transform(fields)
+		if err != nil {
+			return fields, err
+		}
+	}
+	if z.HashChain != nil {
+		return z.HashChain.link(fields, add)
+	}
+	return fields, nil
 }
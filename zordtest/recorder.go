@@ -0,0 +1,208 @@
+// Package zordtest provides helpers for testing code that logs with
+// github.com/rs/zerolog, by recording events in memory and making assertions
+// about their fields.
+package zordtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/7fffffff/jsonconv"
+	"github.com/7fffffff/zord"
+	"github.com/rs/zerolog"
+)
+
+// Event is an event recorded by a Recorder.
+type Event struct {
+	Raw    []byte       // the event as written to the Recorder
+	Fields []zord.Field // the top level fields in order, or nil if the event couldn't be parsed
+}
+
+// Get returns the JSON encoded value of the first field named key.
+func (e Event) Get(key string) ([]byte, bool) {
+	for _, field := range e.Fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// String returns the value of the first field named key, if it's a string.
+func (e Event) String(key string) (string, bool) {
+	value, ok := e.Get(key)
+	if !ok {
+		return "", false
+	}
+	return jsonconv.Unquote(value)
+}
+
+// Keys returns the keys of the fields of e, in order.
+func (e Event) Keys() []string {
+	keys := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		keys[i] = field.Key
+	}
+	return keys
+}
+
+// Recorder is a zerolog output writer that keeps the events written to it in
+// memory. Events are parsed with zord.ParseFields, after being processed by
+// Writer if it's not nil.
+type Recorder struct {
+	Writer *zord.Writer // processes events before they're recorded, if not nil. Its Output is ignored
+
+	mu     sync.Mutex
+	events []Event
+}
+
+// New creates a new Recorder.
+func New() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Write(event []byte) (n int, err error) {
+	raw := append([]byte(nil), event...)
+	if r.Writer != nil {
+		buf := bytes.NewBuffer(make([]byte, 0, len(event)+1))
+		z := *r.Writer
+		z.Output = buf
+		if _, err = z.Write(event); err != nil {
+			return 0, err
+		}
+		raw = buf.Bytes()
+	}
+	fields, _ := zord.ParseFields(raw)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, Event{Raw: raw, Fields: fields})
+	return len(event), nil
+}
+
+// Events returns the events recorded so far, in the order they were
+// written.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]Event, len(r.events))
+	copy(events, r.events)
+	return events
+}
+
+// Reset forgets the events recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// Logged returns the first recorded event with the given level and message
+// that has all of the given fields. If level or message is "", it matches
+// any event. Field values are compared by their JSON encoding.
+func (r *Recorder) Logged(level, message string, fields ...zord.Field) (Event, bool) {
+	for _, event := range r.Events() {
+		if matches(event, level, message, fields) {
+			return event, true
+		}
+	}
+	return Event{}, false
+}
+
+// AssertLogged reports a test error unless an event with the given level,
+// message and fields was recorded, as determined by Logged.
+func (r *Recorder) AssertLogged(t testing.TB, level, message string, fields ...zord.Field) bool {
+	t.Helper()
+	if _, ok := r.Logged(level, message, fields...); ok {
+		return true
+	}
+	t.Errorf("zordtest: no event with level %q, message %q and fields %s was logged; events:\n%s",
+		level, message, formatFields(fields), r.dump())
+	return false
+}
+
+// AssertNotLogged reports a test error if an event with the given level,
+// message and fields was recorded, as determined by Logged.
+func (r *Recorder) AssertNotLogged(t testing.TB, level, message string, fields ...zord.Field) bool {
+	t.Helper()
+	event, ok := r.Logged(level, message, fields...)
+	if !ok {
+		return true
+	}
+	t.Errorf("zordtest: unexpected event: %s", bytes.TrimSpace(event.Raw))
+	return false
+}
+
+// AssertKeyOrder reports a test error unless the keys of the event at index
+// i of Events() are exactly keys, in the same order.
+func (r *Recorder) AssertKeyOrder(t testing.TB, i int, keys ...string) bool {
+	t.Helper()
+	events := r.Events()
+	if i < 0 || i >= len(events) {
+		t.Errorf("zordtest: no event %d, %d events were recorded", i, len(events))
+		return false
+	}
+	actual := events[i].Keys()
+	if strings.Join(actual, "\x00") != strings.Join(keys, "\x00") || len(actual) != len(keys) {
+		t.Errorf("zordtest: event %d has keys %q, expected %q", i, actual, keys)
+		return false
+	}
+	return true
+}
+
+// F returns a field with key and the JSON encoding of value, for use with
+// Logged and the assertions. It panics if value can't be encoded.
+func F(key string, value interface{}) zord.Field {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Sprintf("zordtest: cannot encode value of %s: %v", key, err))
+	}
+	return zord.Field{Key: key, Value: encoded}
+}
+
+func matches(event Event, level, message string, fields []zord.Field) bool {
+	if event.Fields == nil {
+		return false
+	}
+	if level != "" {
+		if s, ok := event.String(zerolog.LevelFieldName); !ok || s != level {
+			return false
+		}
+	}
+	if message != "" {
+		if s, ok := event.String(zerolog.MessageFieldName); !ok || s != message {
+			return false
+		}
+	}
+fields:
+	for _, expected := range fields {
+		for _, field := range event.Fields {
+			if field.Key == expected.Key && bytes.Equal(field.Value, expected.Value) {
+				continue fields
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func formatFields(fields []zord.Field) string {
+	s := make([]string, len(fields))
+	for i, field := range fields {
+		s[i] = string(jsonconv.Quote(field.Key)) + ":" + string(field.Value)
+	}
+	return "{" + strings.Join(s, ",") + "}"
+}
+
+func (r *Recorder) dump() string {
+	var buf strings.Builder
+	for _, event := range r.Events() {
+		buf.WriteString("\t")
+		buf.Write(bytes.TrimSpace(event.Raw))
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
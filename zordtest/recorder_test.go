@@ -0,0 +1,67 @@
+package zordtest
+
+import (
+	"testing"
+
+	"github.com/7fffffff/zord"
+	"github.com/rs/zerolog"
+)
+
+// recordingTB records the errors reported to a testing.TB
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := New()
+	logger := zerolog.New(recorder)
+	logger.Info().Str("user", "alice").Int("attempt", 2).Msg("login")
+	logger.Warn().Bool("locked", true).Msg("login")
+	recorder.Write([]byte("not json"))
+
+	events := recorder.Events()
+	if len(events) != 3 {
+		t.Fatalf("unexpected number of events: %d", len(events))
+	}
+	if s, ok := events[0].String("user"); !ok || s != "alice" {
+		t.Errorf("unexpected user: %q", s)
+	}
+	if events[2].Fields != nil || string(events[2].Raw) != "not json" {
+		t.Errorf("unexpected event: %+v", events[2])
+	}
+
+	recorder.AssertLogged(t, "info", "login", F("attempt", 2), F("user", "alice"))
+	recorder.AssertLogged(t, "", "login", F("locked", true))
+	recorder.AssertNotLogged(t, "error", "")
+	recorder.AssertKeyOrder(t, 0, "level", "user", "attempt", "message")
+
+	tb := &recordingTB{TB: t}
+	recorder.AssertLogged(tb, "info", "login", F("attempt", 3))
+	recorder.AssertLogged(tb, "debug", "login")
+	recorder.AssertNotLogged(tb, "warn", "login")
+	recorder.AssertKeyOrder(tb, 0, "level", "message", "user", "attempt")
+	recorder.AssertKeyOrder(tb, 3)
+	if len(tb.errors) != 5 {
+		t.Errorf("expected 5 errors, got %d", len(tb.errors))
+	}
+
+	recorder.Reset()
+	if len(recorder.Events()) != 0 {
+		t.Error("events weren't reset")
+	}
+}
+
+func TestRecorderWriter(t *testing.T) {
+	recorder := New()
+	recorder.Writer = zord.NewWriter()
+	logger := zerolog.New(recorder)
+	logger.Info().Str("user", "alice").Msg("login")
+	recorder.AssertKeyOrder(t, 0, "level", "message", "user")
+}